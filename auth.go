@@ -1,6 +1,9 @@
 package gcurl
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // AuthType 定义认证类型
 type AuthType int
@@ -44,6 +47,9 @@ type Authentication struct {
 	QOP       string // quality of protection
 	NC        string // nonce count
 	CNonce    string // client nonce
+	Opaque    string // 服务器返回的 opaque，需要原样带回
+
+	nonceCount uint32 // 当前 nonce 已使用的次数
 
 	// 配置选项
 	Digest bool // 是否强制使用Digest认证
@@ -134,3 +140,114 @@ func (auth *Authentication) String() string {
 		return "Unknown"
 	}
 }
+
+// authChallenge 表示 WWW-Authenticate 或 Proxy-Authenticate 中的一个认证挑战
+type authChallenge struct {
+	Scheme string            // 小写的认证方案，如 "basic"、"digest"
+	Params map[string]string // 挑战参数，键为小写
+}
+
+// findAuthChallenge 返回指定方案的第一个挑战
+func findAuthChallenge(challenges []authChallenge, scheme string) (authChallenge, bool) {
+	for _, ch := range challenges {
+		if ch.Scheme == scheme {
+			return ch, true
+		}
+	}
+	return authChallenge{}, false
+}
+
+// parseAuthChallenges 解析认证挑战头部
+// 一个头部可以包含多个挑战，例如：
+//
+//	Digest realm="api", qop="auth,auth-int", nonce="abc", Basic realm="api"
+func parseAuthChallenges(values []string) []authChallenge {
+	var challenges []authChallenge
+	for _, value := range values {
+		p := &challengeParser{input: value}
+		for {
+			p.skipSeparators()
+			scheme := p.readToken()
+			if scheme == "" {
+				break
+			}
+			ch := authChallenge{Scheme: strings.ToLower(scheme), Params: make(map[string]string)}
+			for {
+				p.skipSeparators()
+				// 下一个 token 后面没有 '=' 时表示新的挑战开始
+				start := p.pos
+				key := p.readToken()
+				p.skipSpaces()
+				if key == "" || !p.consume('=') {
+					p.pos = start
+					break
+				}
+				p.skipSpaces()
+				ch.Params[strings.ToLower(key)] = p.readValue()
+			}
+			challenges = append(challenges, ch)
+		}
+	}
+	return challenges
+}
+
+// challengeParser 是认证挑战头部的简单词法分析器
+type challengeParser struct {
+	input string
+	pos   int
+}
+
+func (p *challengeParser) skipSpaces() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t') {
+		p.pos++
+	}
+}
+
+func (p *challengeParser) skipSeparators() {
+	for p.pos < len(p.input) && strings.IndexByte(" \t,", p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func (p *challengeParser) consume(c byte) bool {
+	if p.pos < len(p.input) && p.input[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *challengeParser) readToken() string {
+	start := p.pos
+	for p.pos < len(p.input) && strings.IndexByte(" \t,=\"", p.input[p.pos]) < 0 {
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// readValue 读取 token 或带引号的字符串（支持反斜杠转义）
+func (p *challengeParser) readValue() string {
+	if !p.consume('"') {
+		// token68 形式的值末尾可能带有 '='
+		value := p.readToken()
+		for p.consume('=') {
+			value += "="
+		}
+		return value
+	}
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && p.pos < len(p.input):
+			b.WriteByte(p.input[p.pos])
+			p.pos++
+		case c == '"':
+			return b.String()
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
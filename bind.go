@@ -0,0 +1,228 @@
+package gcurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ExitCodeInterfaceFailed 与 curl 无法绑定 --interface/--local-port 时的退出码一致
+const ExitCodeInterfaceFailed = 45
+
+// BindError 表示无法按照 --interface 或 --local-port 绑定本地地址
+// 可以通过 errors.As 从 Execute 返回的错误中取出
+type BindError struct {
+	Local string // 尝试绑定的本地地址或接口
+	Err   error
+}
+
+// Error 实现 error
+func (e *BindError) Error() string {
+	return fmt.Sprintf("failed to bind local address %s: %v", e.Local, e.Err)
+}
+
+// Unwrap 返回底层错误
+func (e *BindError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode 返回对应的 curl 退出码
+func (e *BindError) ExitCode() int {
+	return ExitCodeInterfaceFailed
+}
+
+// parseLocalPort 解析 --local-port 的 N 或 N-M 格式
+func parseLocalPort(value string) (int, int, error) {
+	first, last, isRange := strings.Cut(value, "-")
+	low, err := strconv.Atoi(strings.TrimSpace(first))
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid port %q", first)
+	}
+	high := low
+	if isRange {
+		if high, err = strconv.Atoi(strings.TrimSpace(last)); err != nil {
+			return 0, 0, fmt.Errorf("invalid port %q", last)
+		}
+	}
+	if low < 1 || high > 65535 || low > high {
+		return 0, 0, fmt.Errorf("port range %s out of 1-65535", value)
+	}
+	return low, high, nil
+}
+
+// localAddresses 返回 --interface 对应的本地 IP
+//   - "if!名称" 只按网络接口名查找
+//   - "host!名称" 只按 IP 或主机名查找
+//   - 没有前缀时先按网络接口名查找，找不到再按 IP 或主机名查找
+func localAddresses(ctx context.Context, resolver *net.Resolver, spec string) ([]net.IP, error) {
+	kind, name, ok := strings.Cut(spec, "!")
+	if !ok || (kind != "if" && kind != "host") {
+		kind, name = "", spec
+	}
+
+	if ip := net.ParseIP(name); ip != nil && kind != "if" {
+		return []net.IP{ip}, nil
+	}
+	if kind != "host" {
+		iface, err := net.InterfaceByName(name)
+		if err == nil {
+			return interfaceAddresses(iface)
+		}
+		if kind == "if" {
+			return nil, err
+		}
+	}
+
+	addrs, err := resolver.LookupIPAddr(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	ips := make([]net.IP, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP)
+	}
+	return ips, nil
+}
+
+// interfaceAddresses 返回网络接口上可以用来绑定的地址，IPv4 在前
+// IPv6 链路本地地址需要指定 zone，不适合作为出口地址，这里跳过
+func interfaceAddresses(iface *net.Interface) ([]net.IP, error) {
+	addrs, err := iface.Addrs()
+	if err != nil {
+		return nil, err
+	}
+	var v4, v6 []net.IP
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		switch {
+		case ipNet.IP.To4() != nil:
+			v4 = append(v4, ipNet.IP)
+		case !ipNet.IP.IsLinkLocalUnicast():
+			v6 = append(v6, ipNet.IP)
+		}
+	}
+	ips := append(v4, v6...)
+	if len(ips) == 0 {
+		return nil, fmt.Errorf("interface %s has no usable address", iface.Name)
+	}
+	return ips, nil
+}
+
+// ipNetwork 按照 -4/-6 限制网络类型
+func (d *dialer) ipNetwork(network string) string {
+	if network != "tcp" {
+		return network
+	}
+	switch d.ipVersion {
+	case 4:
+		return "tcp4"
+	case 6:
+		return "tcp6"
+	}
+	return network
+}
+
+// allowsIP 判断地址是否符合 -4/-6 的限制
+func (d *dialer) allowsIP(ip net.IP) bool {
+	switch d.ipVersion {
+	case 4:
+		return ip.To4() != nil
+	case 6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// filterAddresses 去掉不符合 -4/-6 限制的地址
+func (d *dialer) filterAddresses(addresses []string) []string {
+	if d.ipVersion == 0 {
+		return addresses
+	}
+	var filtered []string
+	for _, addr := range addresses {
+		if ip := net.ParseIP(addr); ip == nil || d.allowsIP(ip) {
+			filtered = append(filtered, addr)
+		}
+	}
+	return filtered
+}
+
+// dialAddr 按照 -4/-6、--interface 和 --local-port 建立到 addr 的连接
+func (d *dialer) dialAddr(ctx context.Context, network, addr string) (net.Conn, error) {
+	network = d.ipNetwork(network)
+	if d.iface == "" && d.localPortLow == 0 {
+		return d.base.DialContext(ctx, network, addr)
+	}
+
+	locals := []net.IP{nil}
+	if d.iface != "" {
+		ips, err := localAddresses(ctx, d.resolver, d.iface)
+		if err != nil {
+			return nil, &BindError{Local: d.iface, Err: err}
+		}
+		locals = locals[:0]
+		for _, ip := range ips {
+			if d.allowsIP(ip) {
+				locals = append(locals, ip)
+			}
+		}
+		if len(locals) == 0 {
+			return nil, &BindError{Local: d.iface, Err: errors.New("no address of the requested IP version")}
+		}
+	}
+
+	// 依次使用每个本地地址，远端地址的协议族必须与本地地址一致
+	var lastErr error
+	for _, ip := range locals {
+		localNetwork := network
+		if ip != nil && network == "tcp" {
+			if ip.To4() != nil {
+				localNetwork = "tcp4"
+			} else {
+				localNetwork = "tcp6"
+			}
+		}
+		conn, err := d.dialFrom(ctx, localNetwork, addr, ip)
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
+
+// dialFrom 从本地地址 ip 建立连接，指定了 --local-port 时依次尝试范围内的端口
+func (d *dialer) dialFrom(ctx context.Context, network, addr string, ip net.IP) (net.Conn, error) {
+	base := *d.base
+	if d.localPortLow == 0 {
+		base.LocalAddr = &net.TCPAddr{IP: ip}
+		return base.DialContext(ctx, network, addr)
+	}
+
+	for port := d.localPortLow; port <= d.localPortHigh; port++ {
+		base.LocalAddr = &net.TCPAddr{IP: ip, Port: port}
+		conn, err := base.DialContext(ctx, network, addr)
+		if err == nil {
+			return conn, nil
+		}
+		// 与 curl 一致，只有端口被占用时才尝试下一个端口
+		if !errors.Is(err, syscall.EADDRINUSE) {
+			return nil, err
+		}
+	}
+	local := fmt.Sprintf("port %d-%d", d.localPortLow, d.localPortHigh)
+	if ip != nil {
+		local = fmt.Sprintf("%s %s", ip, local)
+	}
+	return nil, &BindError{Local: local, Err: syscall.EADDRINUSE}
+}
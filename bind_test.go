@@ -0,0 +1,196 @@
+package gcurl
+
+import (
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestParseLocalPort(t *testing.T) {
+	tests := []struct {
+		value     string
+		low, high int
+		wantErr   bool
+	}{
+		{"8000", 8000, 8000, false},
+		{"4000-4010", 4000, 4010, false},
+		{"0", 0, 0, true},
+		{"70000", 0, 0, true},
+		{"10-5", 0, 0, true},
+		{"abc", 0, 0, true},
+		{"1-x", 0, 0, true},
+	}
+	for _, tt := range tests {
+		low, high, err := parseLocalPort(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseLocalPort(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if low != tt.low || high != tt.high {
+			t.Errorf("parseLocalPort(%q) = %d-%d, want %d-%d", tt.value, low, high, tt.low, tt.high)
+		}
+	}
+}
+
+func TestBindOptionsParsing(t *testing.T) {
+	curl, err := Parse(`curl -4 --interface host!127.0.0.1 --local-port 4000-4010 https://example.com`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if curl.IPVersion != 4 || curl.Interface != "host!127.0.0.1" || curl.LocalPort != "4000-4010" {
+		t.Errorf("unexpected options: ipv=%d interface=%q port=%q", curl.IPVersion, curl.Interface, curl.LocalPort)
+	}
+
+	curl, err = Parse(`curl --ipv6 https://example.com`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if curl.IPVersion != 6 {
+		t.Errorf("IPVersion = %d, want 6", curl.IPVersion)
+	}
+
+	for _, cmd := range []string{
+		`curl --local-port 0 https://example.com`,
+		`curl --local-port 9-1 https://example.com`,
+		`curl --interface if! https://example.com`,
+	} {
+		if _, err := Parse(cmd); err == nil {
+			t.Errorf("expected error for %s", cmd)
+		}
+	}
+}
+
+// bindTestServer 返回记录客户端地址的服务器
+func bindTestServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// remoteAddr 执行命令并返回服务器看到的客户端地址
+func remoteAddr(t *testing.T, cmd string) (string, string) {
+	t.Helper()
+	curl, err := Parse(cmd)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	resp, err := curl.Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	host, port, err := net.SplitHostPort(resp.ContentString())
+	if err != nil {
+		t.Fatalf("invalid remote address %q", resp.ContentString())
+	}
+	return host, port
+}
+
+func TestLocalPortRange(t *testing.T) {
+	srv := bindTestServer(t)
+
+	// 占用范围内的第一个端口，确认会继续尝试下一个
+	busy, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer busy.Close()
+	low := busy.Addr().(*net.TCPAddr).Port
+	if low > 65535-20 {
+		t.Skip("listener port too close to the end of the range")
+	}
+
+	_, port := remoteAddr(t, `curl --local-port `+strconv.Itoa(low)+`-`+strconv.Itoa(low+20)+` `+srv.URL)
+	got, _ := strconv.Atoi(port)
+	if got <= low || got > low+20 {
+		t.Errorf("local port = %d, want within %d-%d", got, low+1, low+20)
+	}
+
+	curl, err := Parse(`curl --local-port ` + strconv.Itoa(low) + ` ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	_, err = curl.Execute()
+	var bindErr *BindError
+	if !errors.As(err, &bindErr) {
+		t.Fatalf("expected BindError, got %v", err)
+	}
+	if code := curl.exitCode(nil, err); code != ExitCodeInterfaceFailed {
+		t.Errorf("exit code = %d, want %d", code, ExitCodeInterfaceFailed)
+	}
+}
+
+func TestInterfaceBinding(t *testing.T) {
+	srv := bindTestServer(t)
+
+	t.Run("IP", func(t *testing.T) {
+		if host, _ := remoteAddr(t, `curl --interface 127.0.0.1 `+srv.URL); host != "127.0.0.1" {
+			t.Errorf("remote host = %s", host)
+		}
+	})
+
+	t.Run("Interface name", func(t *testing.T) {
+		ifaces, err := net.Interfaces()
+		if err != nil {
+			t.Skip(err)
+		}
+		var loopback string
+		for _, iface := range ifaces {
+			if iface.Flags&net.FlagLoopback != 0 {
+				loopback = iface.Name
+				break
+			}
+		}
+		if loopback == "" {
+			t.Skip("no loopback interface")
+		}
+		if host, _ := remoteAddr(t, `curl -4 --interface if!`+loopback+` `+srv.URL); host != "127.0.0.1" {
+			t.Errorf("remote host = %s", host)
+		}
+	})
+
+	t.Run("Unknown interface", func(t *testing.T) {
+		curl, err := Parse(`curl --interface if!nonexistent0 ` + srv.URL)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		_, err = curl.Execute()
+		var bindErr *BindError
+		if !errors.As(err, &bindErr) {
+			t.Errorf("expected BindError, got %v", err)
+		}
+	})
+}
+
+func TestIPVersion(t *testing.T) {
+	srv := bindTestServer(t)
+	port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+
+	// --resolve 中的 IPv6 地址在 -4 时被跳过
+	host, _ := remoteAddr(t, `curl -4 --resolve example.test:`+port+`:[::1],127.0.0.1 http://example.test:`+port+`/`)
+	if host != "127.0.0.1" {
+		t.Errorf("remote host = %s", host)
+	}
+
+	// -6 不能连接到 IPv4 地址
+	curl, err := Parse(`curl -6 ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := curl.Execute(); err == nil {
+		t.Error("expected -6 to refuse an IPv4 address")
+	}
+
+	curl, err = Parse(`curl -6 --resolve example.test:` + port + `:127.0.0.1 http://example.test:` + port + `/`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := curl.Execute(); err == nil || !strings.Contains(err.Error(), "no IPv6 address") {
+		t.Errorf("expected no IPv6 address error, got %v", err)
+	}
+}
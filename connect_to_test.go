@@ -1,6 +1,12 @@
 package gcurl
 
 import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -35,6 +41,20 @@ func TestConnectToOption(t *testing.T) {
 			expected:    []string{"::proxy.example.com:8080"},
 			description: "通配符源主机（任意主机端口到代理）",
 		},
+		{
+			name:        "IPv6 target",
+			command:     `curl https://example.com --connect-to example.com:443:[::1]:8443`,
+			expectError: false,
+			expected:    []string{"example.com:443:[::1]:8443"},
+			description: "目标是方括号中的 IPv6 地址",
+		},
+		{
+			name:        "IPv6 source",
+			command:     `curl http://[::1]/ --connect-to [::1]:80:localhost:81`,
+			expectError: false,
+			expected:    []string{"[::1]:80:localhost:81"},
+			description: "源主机是方括号中的 IPv6 地址",
+		},
 		{
 			name:        "Invalid format - missing parts",
 			command:     `curl https://example.com --connect-to example.com:443:127.0.0.1`,
@@ -201,3 +221,103 @@ func TestConnectToVerboseOutput(t *testing.T) {
 		t.Error("Should show wildcard connection redirect")
 	}
 }
+
+// TestConnectToExecution 测试 --connect-to 在真实请求中生效，且 Host/SNI/证书校验保持原主机
+func TestConnectToExecution(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("host=" + r.Host + " sni=" + r.TLS.ServerName))
+	}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	pool := x509.NewCertPool()
+	pool.AddCert(srv.Certificate())
+
+	testCases := []struct {
+		name      string
+		connectTo []string
+	}{
+		{name: "Exact mapping", connectTo: []string{"example.com:443:127.0.0.1:" + port}},
+		{name: "Any host", connectTo: []string{":443:127.0.0.1:" + port}},
+		{name: "Any port", connectTo: []string{"example.com::127.0.0.1:" + port}},
+		{name: "First match wins", connectTo: []string{"example.com:443:127.0.0.1:" + port, "::127.0.0.2:1"}},
+		{name: "Non matching entry skipped", connectTo: []string{"other.com:443:127.0.0.2:1", "::127.0.0.1:" + port}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			command := `curl https://example.com/`
+			for _, mapping := range tc.connectTo {
+				command += " --connect-to " + mapping
+			}
+			curl, err := Parse(command)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			ses := curl.CreateSession()
+			ses.Config().SetTLSConfig(&tls.Config{RootCAs: pool})
+			resp, err := curl.CreateRequest(ses).Execute()
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			if resp.ContentString() != "host=example.com sni=example.com" {
+				t.Errorf("Unexpected response: %s", resp.ContentString())
+			}
+		})
+	}
+}
+
+// TestConnectToWithResolve 测试 --resolve 作用于 --connect-to 之后的目标主机
+func TestConnectToWithResolve(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+	command := fmt.Sprintf(`curl http://api.example.com/ --connect-to api.example.com:80:backend.internal:%s --resolve backend.internal:%s:127.0.0.1`, port, port)
+	curl, err := Parse(command)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	resp, err := curl.Request().Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.ContentString() != "api.example.com" {
+		t.Errorf("Expected Host header api.example.com, got %s", resp.ContentString())
+	}
+}
+
+func TestParseConnectToEntryIPv6(t *testing.T) {
+	tests := []struct {
+		mapping string
+		want    connectToEntry
+	}{
+		{"example.com:443:[::1]:8443", connectToEntry{"example.com", "443", "::1", "8443"}},
+		{"[::1]:80:host:81", connectToEntry{"::1", "80", "host", "81"}},
+		{"[2001:db8::1]::[2001:db8::2]:", connectToEntry{"2001:db8::1", "", "2001:db8::2", ""}},
+		{"::proxy.example.com:8080", connectToEntry{"", "", "proxy.example.com", "8080"}},
+	}
+	for _, tt := range tests {
+		entry, err := parseConnectToEntry(tt.mapping)
+		if err != nil {
+			t.Errorf("parseConnectToEntry(%q) failed: %v", tt.mapping, err)
+			continue
+		}
+		if *entry != tt.want {
+			t.Errorf("parseConnectToEntry(%q) = %+v, want %+v", tt.mapping, *entry, tt.want)
+		}
+	}
+
+	// 映射到 IPv6 地址后与端口正确组合
+	d := &dialer{}
+	entry, _ := parseConnectToEntry("example.com:443:[::1]:8443")
+	d.connectTo = append(d.connectTo, entry)
+	if host, port := d.rewriteConnectTo("example.com", "443"); net.JoinHostPort(host, port) != "[::1]:8443" {
+		t.Errorf("rewriteConnectTo = %s", net.JoinHostPort(host, port))
+	}
+}
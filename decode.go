@@ -0,0 +1,237 @@
+package gcurl
+
+import (
+	"bufio"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// ExitCodeBadContentEncoding 与 curl 无法解码响应体时的退出码一致
+const ExitCodeBadContentEncoding = 61
+
+// acceptEncoding 是 --compressed 发送的 Accept-Encoding，与 curl 支持的编码一致
+const acceptEncoding = "deflate, gzip, br, zstd"
+
+// ContentEncodingError 表示响应体无法按照 Content-Encoding 解码
+// 可以通过 errors.As 从 Execute 返回的错误中取出
+type ContentEncodingError struct {
+	Encoding string // 解码失败的编码
+	Err      error
+}
+
+// Error 实现 error
+func (e *ContentEncodingError) Error() string {
+	return fmt.Sprintf("failed to decode %s content: %v", e.Encoding, e.Err)
+}
+
+// Unwrap 返回底层错误
+func (e *ContentEncodingError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode 返回对应的 curl 退出码
+func (e *ContentEncodingError) ExitCode() int {
+	return ExitCodeBadContentEncoding
+}
+
+// contentEncodings 返回响应按顺序应用的编码，忽略 identity
+// 存在无法识别的编码时返回 nil，与 curl 一致不解码，原样输出
+func contentEncodings(header http.Header) []string {
+	var encodings []string
+	for _, value := range header.Values("Content-Encoding") {
+		for _, encoding := range strings.Split(value, ",") {
+			encoding = strings.ToLower(strings.TrimSpace(encoding))
+			switch encoding {
+			case "", "identity":
+			case "gzip", "x-gzip", "deflate", "br", "zstd":
+				encodings = append(encodings, encoding)
+			default:
+				return nil
+			}
+		}
+	}
+	return encodings
+}
+
+// contentDecoder 是一个 requests 中间件，按照 Content-Encoding 解码响应体，支持叠加的多个编码
+// 需要放在统计数据量的 statsMiddleware 之后，这样 size_download 统计的是压缩后的字节数
+type contentDecoder struct {
+	curl *CURL
+}
+
+// BeforeRequest 实现 requests.Middleware
+func (d *contentDecoder) BeforeRequest(req *http.Request) error {
+	return nil
+}
+
+// AfterResponse 实现 requests.Middleware
+// 只替换响应体，头部保持不变，-i/-D/-v 仍然能看到原始的 Content-Encoding
+func (d *contentDecoder) AfterResponse(resp *http.Response) error {
+	if d.curl.Raw || resp.Body == nil || resp.Body == http.NoBody {
+		return nil
+	}
+	if encodings := contentEncodings(resp.Header); len(encodings) > 0 {
+		resp.Body = &decodingReader{body: resp.Body, encodings: encodings}
+	}
+	return nil
+}
+
+// decodingReader 在第一次读取时按照编码的逆序逐层建立解码器
+// 空的响应体（例如 HEAD 或 304）不会因为缺少压缩头而报错
+type decodingReader struct {
+	body      io.ReadCloser
+	encodings []string
+	reader    io.Reader
+	closers   []io.Closer
+	err       error
+	bodyErr   error // 读取原始响应体时的错误，不属于解码错误
+}
+
+// Read 实现 io.Reader
+func (r *decodingReader) Read(p []byte) (int, error) {
+	if r.reader == nil && r.err == nil {
+		r.reader, r.err = r.open()
+	}
+	if r.err != nil {
+		return 0, r.err
+	}
+	n, err := r.reader.Read(p)
+	// 网络错误、--max-filesize 等读取原始数据时的错误原样返回，只有解码器自己的错误才是 ContentEncodingError
+	if err != nil && err != io.EOF && (r.bodyErr == nil || !errors.Is(err, r.bodyErr)) {
+		var encodingErr *ContentEncodingError
+		if !errors.As(err, &encodingErr) {
+			err = &ContentEncodingError{Encoding: strings.Join(r.encodings, ", "), Err: err}
+		}
+	}
+	return n, err
+}
+
+// readBody 读取原始响应体并记录错误
+func (r *decodingReader) readBody(p []byte) (int, error) {
+	n, err := r.body.Read(p)
+	if err != nil && err != io.EOF {
+		r.bodyErr = err
+	}
+	return n, err
+}
+
+// readerFunc 把函数转换为 io.Reader
+type readerFunc func(p []byte) (int, error)
+
+// Read 实现 io.Reader
+func (f readerFunc) Read(p []byte) (int, error) {
+	return f(p)
+}
+
+// open 建立解码器链
+func (r *decodingReader) open() (io.Reader, error) {
+	src := bufio.NewReader(readerFunc(r.readBody))
+	if _, err := src.Peek(1); err != nil {
+		// 空的响应体或者读取错误直接交给调用方
+		return src, nil
+	}
+
+	var reader io.Reader = src
+	for i := len(r.encodings) - 1; i >= 0; i-- {
+		encoding := r.encodings[i]
+		decoded, err := r.decoder(encoding, reader)
+		if err != nil {
+			return nil, &ContentEncodingError{Encoding: encoding, Err: err}
+		}
+		reader = decoded
+	}
+	return reader, nil
+}
+
+// decoder 为一层编码创建解码器
+func (r *decodingReader) decoder(encoding string, src io.Reader) (io.Reader, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(src)
+		if err != nil {
+			return nil, err
+		}
+		r.closers = append(r.closers, gz)
+		return gz, nil
+	case "deflate":
+		// 标准的 deflate 是 zlib 格式，但有些服务端发送的是不带 zlib 头的原始 deflate 数据
+		buffered := bufio.NewReader(src)
+		if head, err := buffered.Peek(2); err == nil && isZlibHeader(head) {
+			zr, err := zlib.NewReader(buffered)
+			if err != nil {
+				return nil, err
+			}
+			r.closers = append(r.closers, zr)
+			return zr, nil
+		}
+		fr := flate.NewReader(buffered)
+		r.closers = append(r.closers, fr)
+		return fr, nil
+	case "br":
+		return brotli.NewReader(src), nil
+	case "zstd":
+		zr, err := zstd.NewReader(src, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		closer := zr.IOReadCloser()
+		r.closers = append(r.closers, closer)
+		return closer, nil
+	}
+	return nil, fmt.Errorf("unsupported content encoding %q", encoding)
+}
+
+// Close 实现 io.Closer
+func (r *decodingReader) Close() error {
+	for _, closer := range r.closers {
+		closer.Close()
+	}
+	return r.body.Close()
+}
+
+// isZlibHeader 判断数据是否以 zlib 头开始（RFC 1950）
+func isZlibHeader(head []byte) bool {
+	return head[0]&0x0f == 8 && (uint16(head[0])<<8|uint16(head[1]))%31 == 0
+}
+
+// hideContentEncoding 把 resp 的头部换成去掉 Content-Encoding 的副本，并返回原始头部
+// 响应体已经由 contentDecoder 解码（或者没有 --compressed、指定了 --raw 时保持原样），requests 不应再按 Content-Encoding 解压
+func hideContentEncoding(resp *http.Response) http.Header {
+	original := resp.Header
+	resp.Header = original.Clone()
+	resp.Header.Del("Content-Encoding")
+	return original
+}
+
+// encodingKeeper 是一个 requests 中间件，在 requests 读取响应体之前隐藏 Content-Encoding
+// 执行结束后通过 restore 把原始头部放回返回的 Response
+type encodingKeeper struct {
+	header http.Header
+}
+
+// BeforeRequest 实现 requests.Middleware
+func (k *encodingKeeper) BeforeRequest(req *http.Request) error {
+	return nil
+}
+
+// AfterResponse 实现 requests.Middleware
+func (k *encodingKeeper) AfterResponse(resp *http.Response) error {
+	k.header = hideContentEncoding(resp)
+	return nil
+}
+
+// restore 把原始头部放回 resp
+func (k *encodingKeeper) restore(resp *http.Response) {
+	if resp != nil && k.header != nil {
+		resp.Header = k.header
+	}
+}
@@ -0,0 +1,203 @@
+package gcurl
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+func TestContentDecoding(t *testing.T) {
+	srv := httptest.NewServer(getTestServer())
+	defer srv.Close()
+	target := srv.URL + "/get/body-compressed"
+
+	tests := []struct {
+		name     string
+		cmd      string
+		encoding string
+	}{
+		{"Compressed picks zstd", `curl --compressed ` + target, "zstd"},
+		{"Brotli", `curl --compressed '` + target + `?encoding=br'`, "br"},
+		{"Gzip", `curl --compressed '` + target + `?encoding=gzip'`, "gzip"},
+		{"Raw deflate", `curl --compressed '` + target + `?encoding=deflate'`, "deflate"},
+		{"Stacked encodings", `curl --compressed '` + target + `?encoding=gzip,br,zstd'`, "gzip, br, zstd"},
+		{"Identity", `curl ` + target, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curl, err := Parse(tt.cmd)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			resp, err := curl.Execute()
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if got := resp.ContentString(); got != "hello compress" {
+				t.Errorf("content = %q", got)
+			}
+			if got := resp.GetHeader().Get("Content-Encoding"); got != tt.encoding {
+				t.Errorf("Content-Encoding = %q, want %q", got, tt.encoding)
+			}
+		})
+	}
+}
+
+func TestContentDecodingRaw(t *testing.T) {
+	srv := httptest.NewServer(getTestServer())
+	defer srv.Close()
+
+	curl, err := Parse(`curl --compressed --raw ` + srv.URL + `/get/body-compressed`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	resp, err := curl.Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	want := compressTestBody("zstd", []byte("hello compress"))
+	if !bytes.Equal(resp.Content(), want) {
+		t.Errorf("--raw content = %q, want the encoded bytes", resp.Content())
+	}
+}
+
+// TestContentDecodingRequiresCompressed 验证与 curl 一致，没有 --compressed 时 Execute 和 Download 都不解码
+func TestContentDecodingRequiresCompressed(t *testing.T) {
+	srv := httptest.NewServer(getTestServer())
+	defer srv.Close()
+	cmd := `curl -H "Accept-Encoding: gzip" ` + srv.URL + `/get/body-compressed`
+	want := compressTestBody("gzip", []byte("hello compress"))
+
+	curl, err := Parse(cmd)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	resp, err := curl.Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !bytes.Equal(resp.Content(), want) {
+		t.Errorf("Execute content = %q, want the encoded bytes", resp.Content())
+	}
+
+	output := filepath.Join(t.TempDir(), "out")
+	curl, err = Parse(cmd + ` -o ` + output)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := curl.Download(); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if data, _ := os.ReadFile(output); !bytes.Equal(data, want) {
+		t.Errorf("Download content = %q, want the encoded bytes", data)
+	}
+}
+
+func TestContentDecodingSizeDownload(t *testing.T) {
+	srv := httptest.NewServer(getTestServer())
+	defer srv.Close()
+
+	out, _ := runWriteOut(t, `curl --compressed -w '%{size_download}' `+srv.URL+`/get/body-compressed`)
+	want := strconv.Itoa(len(compressTestBody("zstd", []byte("hello compress"))))
+	if out != want {
+		t.Errorf("size_download = %s, want compressed size %s", out, want)
+	}
+}
+
+func TestContentDecodingEdgeCases(t *testing.T) {
+	content := []byte("zlib wrapped deflate")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/zlib":
+			var buf bytes.Buffer
+			zw := zlib.NewWriter(&buf)
+			zw.Write(content)
+			zw.Close()
+			w.Header().Set("Content-Encoding", "deflate")
+			w.Write(buf.Bytes())
+		case "/broken":
+			w.Header().Set("Content-Encoding", "gzip")
+			w.Write([]byte("not gzip at all"))
+		case "/unknown":
+			w.Header().Set("Content-Encoding", "compress")
+			w.Write([]byte("as is"))
+		default:
+			// HEAD 和空响应体没有压缩数据
+			w.Header().Set("Content-Encoding", "gzip")
+		}
+	}))
+	defer srv.Close()
+
+	execute := func(cmd string) (string, error) {
+		t.Helper()
+		curl, err := Parse(cmd)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		resp, err := curl.Execute()
+		if err != nil {
+			return "", err
+		}
+		return resp.ContentString(), nil
+	}
+
+	if got, err := execute(`curl --compressed ` + srv.URL + `/zlib`); err != nil || got != string(content) {
+		t.Errorf("zlib deflate = %q, %v", got, err)
+	}
+	if got, err := execute(`curl --compressed ` + srv.URL + `/unknown`); err != nil || got != "as is" {
+		t.Errorf("unknown encoding = %q, %v", got, err)
+	}
+	if got, err := execute(`curl --compressed -I ` + srv.URL + `/empty`); err != nil || got != "" {
+		t.Errorf("HEAD = %q, %v", got, err)
+	}
+	if got, err := execute(`curl --compressed ` + srv.URL + `/empty`); err != nil || got != "" {
+		t.Errorf("empty body = %q, %v", got, err)
+	}
+
+	_, err := execute(`curl --compressed ` + srv.URL + `/broken`)
+	var encodingErr *ContentEncodingError
+	if !errors.As(err, &encodingErr) {
+		t.Fatalf("expected ContentEncodingError, got %v", err)
+	}
+	if code := New().exitCode(nil, err); code != ExitCodeBadContentEncoding {
+		t.Errorf("exit code = %d, want %d", code, ExitCodeBadContentEncoding)
+	}
+}
+
+func TestCompressedOption(t *testing.T) {
+	curl, err := Parse(`curl --compressed https://example.com`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !curl.Compressed || curl.Header.Get("Accept-Encoding") != acceptEncoding {
+		t.Errorf("Compressed = %v, Accept-Encoding = %q", curl.Compressed, curl.Header.Get("Accept-Encoding"))
+	}
+}
+
+func TestDownloadCompressed(t *testing.T) {
+	srv := httptest.NewServer(getTestServer())
+	defer srv.Close()
+	output := filepath.Join(t.TempDir(), "out.txt")
+
+	// 与 curl 一致，--compressed 时保存解码后的数据
+	curl, err := Parse(`curl --compressed -o ` + output + ` ` + srv.URL + `/get/body-compressed`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := curl.Download(); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	got, err := os.ReadFile(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != "hello compress" {
+		t.Errorf("file content = %q", got)
+	}
+}
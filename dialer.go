@@ -0,0 +1,313 @@
+package gcurl
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// resolveEntry 表示一条 --resolve 映射
+type resolveEntry struct {
+	Host      string   // 主机名，"*" 表示任意主机
+	Port      string   // 端口
+	Addresses []string // 按顺序尝试的地址列表
+}
+
+// parseResolveEntry 解析 [+]host:port:address[,address]... 格式的映射
+// curl 用 + 前缀替换 DNS 缓存中已有的条目；这里没有跨请求的 DNS 缓存，映射总是优先于 DNS 解析，
+// 因此 + 前缀只为兼容 curl 的写法而接受，与不带前缀的形式效果相同
+func parseResolveEntry(mapping string) (*resolveEntry, error) {
+	parts := strings.SplitN(mapping, ":", 3)
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("invalid --resolve format, expected host:port:address, got: %s", mapping)
+	}
+
+	entry := &resolveEntry{Host: strings.TrimPrefix(parts[0], "+"), Port: parts[1]}
+	if entry.Host == "" {
+		return nil, fmt.Errorf("missing host in --resolve: %s", mapping)
+	}
+
+	for _, addr := range strings.Split(parts[2], ",") {
+		addr = strings.TrimSpace(addr)
+		// IPv6 地址可以写成 [::1] 的形式
+		addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		if addr != "" {
+			entry.Addresses = append(entry.Addresses, addr)
+		}
+	}
+	if len(entry.Addresses) == 0 {
+		return nil, fmt.Errorf("missing address in --resolve: %s", mapping)
+	}
+
+	return entry, nil
+}
+
+// matches 判断映射是否适用于 host:port
+func (e *resolveEntry) matches(host, port string) bool {
+	if e.Port != port {
+		return false
+	}
+	return e.Host == "*" || strings.EqualFold(e.Host, host)
+}
+
+// connectToEntry 表示一条 --connect-to 映射
+type connectToEntry struct {
+	SourceHost string // 空字符串表示任意主机
+	SourcePort string // 空字符串表示任意端口
+	TargetHost string
+	TargetPort string // 空字符串表示保持原端口
+}
+
+// parseConnectToEntry 解析 HOST1:PORT1:HOST2:PORT2 格式的映射，IPv6 地址写成 [::1] 的形式
+func parseConnectToEntry(mapping string) (*connectToEntry, error) {
+	parts := splitConnectTo(mapping)
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("invalid --connect-to format, expected HOST1:PORT1:HOST2:PORT2, got: %s", mapping)
+	}
+	return &connectToEntry{
+		SourceHost: parts[0],
+		SourcePort: parts[1],
+		TargetHost: parts[2],
+		TargetPort: parts[3],
+	}, nil
+}
+
+// splitConnectTo 按 ":" 拆分 --connect-to 映射，方括号中的 IPv6 地址作为一个字段，并去掉方括号
+func splitConnectTo(mapping string) []string {
+	var parts []string
+	rest := mapping
+	for {
+		if strings.HasPrefix(rest, "[") {
+			if end := strings.Index(rest, "]"); end > 0 && (end+1 == len(rest) || rest[end+1] == ':') {
+				parts = append(parts, rest[1:end])
+				if end+1 == len(rest) {
+					return parts
+				}
+				rest = rest[end+2:]
+				continue
+			}
+		}
+		i := strings.IndexByte(rest, ':')
+		if i < 0 {
+			return append(parts, rest)
+		}
+		parts = append(parts, rest[:i])
+		rest = rest[i+1:]
+	}
+}
+
+// matches 判断映射是否适用于 host:port
+func (e *connectToEntry) matches(host, port string) bool {
+	if e.SourceHost != "" && !strings.EqualFold(e.SourceHost, host) {
+		return false
+	}
+	return e.SourcePort == "" || e.SourcePort == port
+}
+
+// dialer 在真正建立连接前应用 --connect-to 和 --resolve 映射
+// 并按照 --interface、--local-port 和 -4/-6 选择本地地址和协议族
+type dialer struct {
+	base      *net.Dialer
+	resolver  *net.Resolver
+	resolves  []*resolveEntry
+	connectTo []*connectToEntry
+
+	connectTimeout time.Duration // 整个连接阶段（含DNS解析）的超时
+	dnsTimeout     time.Duration // DNS解析的超时
+
+	iface         string // --interface 指定的接口名、IP 或主机名
+	localPortLow  int    // --local-port 的范围，0 表示不限制
+	localPortHigh int
+	ipVersion     int // -4/-6，0 表示不限制
+
+	noDelay bool // --tcp-nodelay，为 false 时开启 Nagle 算法
+
+	unixSocket string // --unix-socket/--abstract-unix-socket，不为空时所有连接都连到这个套接字
+}
+
+// newDialer 根据 CURL 的配置创建拨号器
+func (curl *CURL) newDialer() *dialer {
+	d := &dialer{
+		base:           &net.Dialer{KeepAlive: curl.keepAliveInterval()},
+		resolver:       net.DefaultResolver,
+		connectTimeout: curl.ConnectTimeout,
+		dnsTimeout:     curl.DNSTimeout,
+		iface:          curl.Interface,
+		ipVersion:      curl.IPVersion,
+		noDelay:        curl.TCPNoDelay,
+		unixSocket:     curl.unixSocketAddr(),
+	}
+	if curl.TCPFastOpen {
+		d.base.Control = func(network, address string, c syscall.RawConn) error {
+			return setTCPFastOpen(c)
+		}
+	}
+	if curl.LocalPort != "" {
+		if low, high, err := parseLocalPort(curl.LocalPort); err == nil {
+			d.localPortLow, d.localPortHigh = low, high
+		}
+	}
+
+	// 后出现的映射覆盖先出现的同名映射，与 curl 行为一致
+	for i := len(curl.Resolve) - 1; i >= 0; i-- {
+		entry, err := parseResolveEntry(curl.Resolve[i])
+		if err != nil {
+			continue
+		}
+		d.resolves = append(d.resolves, entry)
+	}
+
+	for _, mapping := range curl.ConnectTo {
+		entry, err := parseConnectToEntry(mapping)
+		if err != nil {
+			continue
+		}
+		d.connectTo = append(d.connectTo, entry)
+	}
+
+	return d
+}
+
+// rewriteConnectTo 返回实际要连接的 host 和 port，只使用第一条匹配的映射
+func (d *dialer) rewriteConnectTo(host, port string) (string, string) {
+	for _, entry := range d.connectTo {
+		if !entry.matches(host, port) {
+			continue
+		}
+		if entry.TargetHost != "" {
+			host = entry.TargetHost
+		}
+		if entry.TargetPort != "" {
+			port = entry.TargetPort
+		}
+		break
+	}
+	return host, port
+}
+
+// lookupResolve 查找 host:port 对应的地址列表，精确匹配优先于 * 通配符
+func (d *dialer) lookupResolve(host, port string) []string {
+	var wildcard []string
+	for _, entry := range d.resolves {
+		if !entry.matches(host, port) {
+			continue
+		}
+		if entry.Host != "*" {
+			return entry.Addresses
+		}
+		if wildcard == nil {
+			wildcard = entry.Addresses
+		}
+	}
+	return wildcard
+}
+
+// lookupHost 在 DNS 超时限制内解析主机名，没有指定 --dns-timeout 时不限制
+func (d *dialer) lookupHost(ctx context.Context, host string) ([]string, error) {
+	lookupCtx := ctx
+	if d.dnsTimeout > 0 {
+		var cancel context.CancelFunc
+		lookupCtx, cancel = context.WithTimeout(ctx, d.dnsTimeout)
+		defer cancel()
+	}
+
+	addrs, err := d.resolver.LookupIPAddr(lookupCtx, host)
+	if err != nil {
+		if lookupCtx.Err() == context.DeadlineExceeded && ctx.Err() == nil {
+			return nil, fmt.Errorf("resolving host %s timed out after %v: %w", host, d.dnsTimeout, err)
+		}
+		return nil, err
+	}
+
+	ips := make([]string, 0, len(addrs))
+	for _, addr := range addrs {
+		ips = append(ips, addr.IP.String())
+	}
+	return ips, nil
+}
+
+// DialContext 实现 http.Transport 的 DialContext
+// 只改变 TCP 连接的目标地址，Host 头、TLS SNI 和证书校验仍然使用 URL 中的主机
+func (d *dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	// --connect-timeout 只限制建立连接的阶段，不影响之后的数据传输
+	if d.connectTimeout > 0 {
+		parent := ctx
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.connectTimeout)
+		defer cancel()
+
+		conn, err := d.connect(ctx, network, addr)
+		if err != nil && ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+			return nil, fmt.Errorf("connection to %s timed out after %v: %w", addr, d.connectTimeout, err)
+		}
+		return d.tune(conn), err
+	}
+	conn, err := d.connect(ctx, network, addr)
+	return d.tune(conn), err
+}
+
+// connect 按照请求选择的代理建立连接，没有代理时直接连接
+func (d *dialer) connect(ctx context.Context, network, addr string) (net.Conn, error) {
+	if route := proxyFromContext(ctx); route != nil {
+		return d.dialProxy(ctx, route, network, addr)
+	}
+	return d.dial(ctx, network, addr)
+}
+
+// tune 设置连接建立之后才能修改的 TCP 选项
+// net 包在连接建立后总是开启 TCP_NODELAY，因此只能在这里关闭
+func (d *dialer) tune(conn net.Conn) net.Conn {
+	if tcpConn, ok := conn.(*net.TCPConn); ok && !d.noDelay {
+		tcpConn.SetNoDelay(false)
+	}
+	return conn
+}
+
+// dial 应用 --connect-to 和 --resolve 映射后建立连接
+func (d *dialer) dial(ctx context.Context, network, addr string) (net.Conn, error) {
+	// Unix 套接字忽略 URL 中的主机和端口，它们只用于 Host 头和 TLS
+	if d.unixSocket != "" {
+		return d.base.DialContext(ctx, "unix", d.unixSocket)
+	}
+
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return d.dialAddr(ctx, network, addr)
+	}
+
+	// 先应用 --connect-to，再对新的目标应用 --resolve
+	host, port = d.rewriteConnectTo(host, port)
+
+	addresses := d.lookupResolve(host, port)
+	if len(addresses) == 0 {
+		// 指定了 DNS 超时时由我们自己解析，否则交给 net.Dialer
+		if d.dnsTimeout <= 0 || net.ParseIP(host) != nil {
+			return d.dialAddr(ctx, network, net.JoinHostPort(host, port))
+		}
+		if addresses, err = d.lookupHost(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+
+	// -4/-6 同样限制 --resolve 指定的地址
+	if addresses = d.filterAddresses(addresses); len(addresses) == 0 {
+		return nil, fmt.Errorf("no IPv%d address for host %s", d.ipVersion, host)
+	}
+
+	// 依次尝试每个地址，全部失败时返回最后一个错误
+	var lastErr error
+	for _, ip := range addresses {
+		conn, err := d.dialAddr(ctx, network, net.JoinHostPort(ip, port))
+		if err == nil {
+			return conn, nil
+		}
+		lastErr = err
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, lastErr
+}
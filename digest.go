@@ -0,0 +1,264 @@
+package gcurl
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/474420502/requests"
+)
+
+// digestHashFunc 根据算法名称返回对应的哈希函数，支持 RFC 7616 定义的算法
+func digestHashFunc(algorithm string) (func() hash.Hash, bool) {
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "", "MD5":
+		return md5.New, true
+	case "SHA-256":
+		return sha256.New, true
+	case "SHA-512-256":
+		return sha512.New512_256, true
+	default:
+		return nil, false
+	}
+}
+
+// applyDigestChallenge 使用服务器的 Digest 挑战更新认证状态
+// nonce 发生变化时重置 nonce count 并生成新的 cnonce
+func (auth *Authentication) applyDigestChallenge(ch authChallenge) error {
+	algorithm := ch.Params["algorithm"]
+	if _, ok := digestHashFunc(algorithm); !ok {
+		return fmt.Errorf("unsupported digest algorithm: %s", algorithm)
+	}
+
+	nonce := ch.Params["nonce"]
+	if nonce == "" {
+		return fmt.Errorf("digest challenge missing nonce")
+	}
+
+	// 同时支持 auth 和 auth-int 时优先使用 auth（与 curl 一致）
+	qop := ""
+	if qopList := ch.Params["qop"]; qopList != "" {
+		for _, option := range strings.Split(qopList, ",") {
+			option = strings.TrimSpace(option)
+			if option == "auth" {
+				qop = "auth"
+				break
+			}
+			if option == "auth-int" {
+				qop = "auth-int"
+			}
+		}
+		if qop == "" {
+			return fmt.Errorf("unsupported digest qop: %s", qopList)
+		}
+	}
+
+	if nonce != auth.Nonce || auth.CNonce == "" {
+		cnonce, err := newCNonce()
+		if err != nil {
+			return err
+		}
+		auth.CNonce = cnonce
+		auth.nonceCount = 0
+	}
+
+	auth.Realm = ch.Params["realm"]
+	auth.Nonce = nonce
+	auth.Opaque = ch.Params["opaque"]
+	auth.Algorithm = algorithm
+	auth.QOP = qop
+	return nil
+}
+
+// digestAuthorization 计算一次请求的 Digest Authorization 头部值
+// body 只在 qop=auth-int 时参与计算；每次调用都会递增 nonce count
+func (auth *Authentication) digestAuthorization(method, uri string, body []byte) string {
+	newHash, _ := digestHashFunc(auth.Algorithm)
+	h := func(s string) string {
+		hh := newHash()
+		io.WriteString(hh, s)
+		return hex.EncodeToString(hh.Sum(nil))
+	}
+
+	auth.nonceCount++
+	auth.NC = fmt.Sprintf("%08x", auth.nonceCount)
+	auth.URI = uri
+
+	ha1 := h(auth.Username + ":" + auth.Realm + ":" + auth.Password)
+	if strings.HasSuffix(strings.ToUpper(auth.Algorithm), "-SESS") {
+		ha1 = h(ha1 + ":" + auth.Nonce + ":" + auth.CNonce)
+	}
+
+	ha2 := h(method + ":" + uri)
+	if auth.QOP == "auth-int" {
+		ha2 = h(method + ":" + uri + ":" + h(string(body)))
+	}
+
+	if auth.QOP == "" {
+		auth.Response = h(ha1 + ":" + auth.Nonce + ":" + ha2)
+	} else {
+		auth.Response = h(strings.Join([]string{ha1, auth.Nonce, auth.NC, auth.CNonce, auth.QOP, ha2}, ":"))
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `Digest username="%s", realm="%s", nonce="%s", uri="%s"`,
+		quoteEscape(auth.Username), quoteEscape(auth.Realm), auth.Nonce, uri)
+	if auth.Algorithm != "" {
+		fmt.Fprintf(&b, ", algorithm=%s", auth.Algorithm)
+	}
+	fmt.Fprintf(&b, `, response="%s"`, auth.Response)
+	if auth.Opaque != "" {
+		fmt.Fprintf(&b, `, opaque="%s"`, auth.Opaque)
+	}
+	if auth.QOP != "" {
+		fmt.Fprintf(&b, `, qop=%s, nc=%s, cnonce="%s"`, auth.QOP, auth.NC, auth.CNonce)
+	}
+	return b.String()
+}
+
+// quoteEscape 转义 quoted-string 中的反斜杠和引号
+func quoteEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// newCNonce 生成随机的客户端 nonce
+func newCNonce() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate cnonce: %w", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// digestTransport 实现 Digest 认证的挑战-响应流程
+// 第一次请求不带认证信息，收到 401 的 Digest 挑战后计算 Authorization 并重发；
+// 之后同一 Session 中发往同一个源的请求直接复用该挑战，nonce count 依次递增
+type digestTransport struct {
+	base    http.RoundTripper
+	auth    *Authentication
+	trusted bool // --location-trusted，重定向到其他源时同样应答挑战
+
+	mu         sync.Mutex
+	challenged string // 应答过挑战的源（scheme://host:port），为空表示还没有收到挑战
+}
+
+// newDigestTransport 创建 Digest 认证的 RoundTripper
+func newDigestTransport(base http.RoundTripper, username, password string) *digestTransport {
+	return &digestTransport{base: base, auth: NewDigestAuth(username, password)}
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *digestTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	// 与 checkRedirect 一致，认证信息只发送给重定向链中最初请求的源，除非指定了 --location-trusted
+	if !t.trusted && !sameOrigin(firstRequest(req), req) {
+		return t.base.RoundTrip(req)
+	}
+	origin := req.URL.Scheme + "://" + req.URL.Host
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	t.mu.Lock()
+	var authorization string
+	if t.challenged == origin {
+		authorization = t.auth.digestAuthorization(req.Method, req.URL.RequestURI(), body)
+	}
+	t.mu.Unlock()
+
+	// 最多重发两次：一次应答初始挑战，一次应答 stale nonce
+	for attempt := 0; ; attempt++ {
+		resp, err := t.base.RoundTrip(cloneRequest(req, body, authorization))
+		if err != nil || resp.StatusCode != http.StatusUnauthorized || attempt >= 2 {
+			return resp, err
+		}
+
+		ch, ok := findAuthChallenge(parseAuthChallenges(resp.Header.Values("WWW-Authenticate")), "digest")
+		if !ok {
+			return resp, nil
+		}
+		// 已经带了认证信息仍被拒绝，且 nonce 并未过期，说明凭据错误
+		if authorization != "" && !strings.EqualFold(ch.Params["stale"], "true") {
+			return resp, nil
+		}
+
+		t.mu.Lock()
+		if err := t.auth.applyDigestChallenge(ch); err != nil {
+			t.mu.Unlock()
+			return resp, nil
+		}
+		t.challenged = origin
+		authorization = t.auth.digestAuthorization(req.Method, req.URL.RequestURI(), body)
+		t.mu.Unlock()
+
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// configureDigest 为 --digest 在 Session 的 Transport 外层包装 Digest 认证
+func (curl *CURL) configureDigest(ses *requests.Session) {
+	if curl.AuthV2 == nil || !curl.AuthV2.IsValid() || curl.AuthV2.Type != "digest" {
+		return
+	}
+	if client := sessionClient(ses); client != nil {
+		transport := newDigestTransport(client.Transport, curl.AuthV2.Username, curl.AuthV2.Password)
+		transport.trusted = curl.LocationTrusted
+		client.Transport = transport
+	}
+}
+
+// firstRequest 沿着重定向链返回最初的请求
+func firstRequest(req *http.Request) *http.Request {
+	for req.Response != nil && req.Response.Request != nil {
+		req = req.Response.Request
+	}
+	return req
+}
+
+// readRequestBody 读取请求体以便在重发时使用，不会消耗原请求的 Body
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	data, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// cloneRequest 复制请求，使用给定的请求体，并在需要时设置 Authorization 头
+func cloneRequest(req *http.Request, body []byte, authorization string) *http.Request {
+	r := req.Clone(req.Context())
+	if body != nil {
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.GetBody = func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(body)), nil
+		}
+		r.ContentLength = int64(len(body))
+	}
+	if authorization != "" {
+		r.Header.Set("Authorization", authorization)
+	}
+	return r
+}
@@ -1,6 +1,17 @@
 package gcurl
 
 import (
+	"crypto/md5"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
 )
 
@@ -168,3 +179,320 @@ func TestDigestAuthenticationMethods(t *testing.T) {
 		t.Errorf("NewDigestAuth() GetAuthHeader() = %v, want empty string (digest requires challenge)", header)
 	}
 }
+
+func TestParseAuthChallenges(t *testing.T) {
+	values := []string{
+		`Basic realm="basic area", Digest realm="http-auth@example.org", qop="auth, auth-int", algorithm=SHA-256, nonce="7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v", opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`,
+		`Digest realm="quoted \"realm\"", nonce=abc, stale=TRUE`,
+		`Negotiate token68==`,
+	}
+
+	challenges := parseAuthChallenges(values)
+	if len(challenges) != 4 {
+		t.Fatalf("Expected 4 challenges, got %d: %+v", len(challenges), challenges)
+	}
+
+	if challenges[0].Scheme != "basic" || challenges[0].Params["realm"] != "basic area" {
+		t.Errorf("Unexpected basic challenge: %+v", challenges[0])
+	}
+
+	digest, ok := findAuthChallenge(challenges, "digest")
+	if !ok {
+		t.Fatal("Digest challenge not found")
+	}
+	if digest.Params["qop"] != "auth, auth-int" {
+		t.Errorf("qop = %q", digest.Params["qop"])
+	}
+	if digest.Params["algorithm"] != "SHA-256" {
+		t.Errorf("algorithm = %q", digest.Params["algorithm"])
+	}
+	if digest.Params["opaque"] != "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS" {
+		t.Errorf("opaque = %q", digest.Params["opaque"])
+	}
+
+	if challenges[2].Params["realm"] != `quoted "realm"` || challenges[2].Params["stale"] != "TRUE" {
+		t.Errorf("Unexpected escaped challenge: %+v", challenges[2])
+	}
+	if challenges[3].Scheme != "negotiate" {
+		t.Errorf("Unexpected token68 challenge: %+v", challenges[3])
+	}
+}
+
+// TestDigestRFC7616Vectors 使用 RFC 7616 第 3.9.1 节的示例验证计算结果
+func TestDigestRFC7616Vectors(t *testing.T) {
+	tests := []struct {
+		algorithm string
+		want      string
+	}{
+		{"MD5", "8ca523f5e9506fed4657c9700eebdbec"},
+		{"SHA-256", "753927fa0e85d155564e2e272a28d1802ca10daf4496794697cf8db5856cb6c1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.algorithm, func(t *testing.T) {
+			auth := NewDigestAuth("Mufasa", "Circle of Life")
+			auth.Realm = "http-auth@example.org"
+			auth.Nonce = "7ypf/xlj9XXwfDPEoM4URrv/xwf94BcCAzFZH4GiTo0v"
+			auth.CNonce = "f2/wE4q74E6zIJEtWaHKaf5wv/H5QzzpXusqGemxURZJ"
+			auth.Opaque = "FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"
+			auth.Algorithm = tt.algorithm
+			auth.QOP = "auth"
+
+			header := auth.digestAuthorization("GET", "/dir/index.html", nil)
+			if auth.Response != tt.want {
+				t.Errorf("response = %s, want %s", auth.Response, tt.want)
+			}
+			if !strings.Contains(header, "nc=00000001") {
+				t.Errorf("Expected nc=00000001 in %s", header)
+			}
+			if !strings.Contains(header, `opaque="FQhe/qaU925kfnzjCev0ciny7QMkPqMAFRtzCUYo5tdS"`) {
+				t.Errorf("Expected opaque in %s", header)
+			}
+		})
+	}
+}
+
+// digestTestServer 是一个简单的 Digest 认证服务端，用于验证客户端的挑战-响应流程
+type digestTestServer struct {
+	algorithm string
+	qop       string
+	user      string
+	pass      string
+
+	mu        sync.Mutex
+	nonce     string
+	staleOnce bool     // 为 true 时第一次通过认证的请求会被要求更换 nonce
+	redirect  string   // 不为空时通过认证的请求被重定向到这个地址
+	ncSeen    []string // 通过认证的请求的 nc 值
+	requests  int      // 收到的请求总数
+}
+
+func (s *digestTestServer) hash(data string) string {
+	var h hash.Hash
+	switch strings.TrimSuffix(s.algorithm, "-sess") {
+	case "SHA-256":
+		h = sha256.New()
+	case "SHA-512-256":
+		h = sha512.New512_256()
+	default:
+		h = md5.New()
+	}
+	io.WriteString(h, data)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (s *digestTestServer) challenge(w http.ResponseWriter, stale bool) {
+	header := fmt.Sprintf(`Digest realm="test", qop="%s", algorithm=%s, nonce="%s", opaque="op"`, s.qop, s.algorithm, s.nonce)
+	if stale {
+		header += ", stale=true"
+	}
+	w.Header().Add("WWW-Authenticate", `Basic realm="test"`)
+	w.Header().Add("WWW-Authenticate", header)
+	w.WriteHeader(http.StatusUnauthorized)
+}
+
+func (s *digestTestServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.requests++
+
+	body, _ := io.ReadAll(r.Body)
+	challenges := parseAuthChallenges([]string{r.Header.Get("Authorization")})
+	ch, ok := findAuthChallenge(challenges, "digest")
+	if !ok {
+		s.challenge(w, false)
+		return
+	}
+
+	p := ch.Params
+	if p["nonce"] != s.nonce {
+		s.challenge(w, true)
+		return
+	}
+
+	ha1 := s.hash(s.user + ":" + p["realm"] + ":" + s.pass)
+	if strings.HasSuffix(s.algorithm, "-sess") {
+		ha1 = s.hash(ha1 + ":" + p["nonce"] + ":" + p["cnonce"])
+	}
+	ha2 := s.hash(r.Method + ":" + p["uri"])
+	if p["qop"] == "auth-int" {
+		ha2 = s.hash(r.Method + ":" + p["uri"] + ":" + s.hash(string(body)))
+	}
+	expected := s.hash(strings.Join([]string{ha1, p["nonce"], p["nc"], p["cnonce"], p["qop"], ha2}, ":"))
+
+	if p["username"] != s.user || p["uri"] != r.URL.RequestURI() || p["opaque"] != "op" || p["response"] != expected {
+		s.challenge(w, false)
+		return
+	}
+
+	if s.staleOnce {
+		s.staleOnce = false
+		s.nonce = s.nonce + "-renewed"
+		s.challenge(w, true)
+		return
+	}
+
+	s.ncSeen = append(s.ncSeen, p["nc"])
+	if s.redirect != "" {
+		http.Redirect(w, r, s.redirect, http.StatusFound)
+		return
+	}
+	w.Write([]byte("authorized:" + string(body)))
+}
+
+func TestDigestExecution(t *testing.T) {
+	tests := []struct {
+		name      string
+		algorithm string
+		qop       string
+		method    string
+		data      string
+	}{
+		{name: "MD5", algorithm: "MD5", qop: "auth", method: "GET"},
+		{name: "MD5-sess", algorithm: "MD5-sess", qop: "auth", method: "GET"},
+		{name: "SHA-256", algorithm: "SHA-256", qop: "auth", method: "GET"},
+		{name: "SHA-512-256", algorithm: "SHA-512-256", qop: "auth", method: "GET"},
+		{name: "auth-int POST", algorithm: "SHA-256", qop: "auth-int", method: "POST", data: "name=gcurl"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &digestTestServer{algorithm: tt.algorithm, qop: tt.qop, user: "user", pass: "secret", nonce: "n1"}
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			cmd := fmt.Sprintf(`curl --digest user:secret %s/protected?x=1`, srv.URL)
+			if tt.data != "" {
+				cmd = fmt.Sprintf(`curl --digest user:secret -d "%s" %s/protected?x=1`, tt.data, srv.URL)
+			}
+			c, err := Parse(cmd)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			resp, err := c.Request().Execute()
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if resp.GetStatusCode() != http.StatusOK {
+				t.Fatalf("Expected 200, got %d", resp.GetStatusCode())
+			}
+			if got := string(resp.Content()); got != "authorized:"+tt.data {
+				t.Errorf("Unexpected body: %q", got)
+			}
+		})
+	}
+}
+
+func TestDigestNonceCount(t *testing.T) {
+	handler := &digestTestServer{algorithm: "MD5", qop: "auth", user: "user", pass: "secret", nonce: "n1"}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	c, err := Parse(`curl --digest user:secret ` + srv.URL + `/protected`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	ses := c.CreateSession()
+	for i := 0; i < 3; i++ {
+		resp, err := c.CreateTemporary(ses).Execute()
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if resp.GetStatusCode() != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.GetStatusCode())
+		}
+	}
+
+	want := []string{"00000001", "00000002", "00000003"}
+	if strings.Join(handler.ncSeen, ",") != strings.Join(want, ",") {
+		t.Errorf("nc values = %v, want %v", handler.ncSeen, want)
+	}
+	// 只有第一次请求需要挑战，之后直接携带认证信息
+	if handler.requests != 4 {
+		t.Errorf("Expected 4 requests to server, got %d", handler.requests)
+	}
+}
+
+func TestDigestStaleNonce(t *testing.T) {
+	handler := &digestTestServer{algorithm: "MD5", qop: "auth", user: "user", pass: "secret", nonce: "n1", staleOnce: true}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	c, err := Parse(`curl --digest user:secret ` + srv.URL + `/protected`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	resp, err := c.Request().Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.GetStatusCode() != http.StatusOK {
+		t.Fatalf("Expected 200 after stale nonce, got %d", resp.GetStatusCode())
+	}
+	// nonce 更换后 nc 从 1 重新开始
+	if len(handler.ncSeen) != 1 || handler.ncSeen[0] != "00000001" {
+		t.Errorf("nc values = %v, want [00000001]", handler.ncSeen)
+	}
+}
+
+func TestDigestWrongPassword(t *testing.T) {
+	handler := &digestTestServer{algorithm: "MD5", qop: "auth", user: "user", pass: "secret", nonce: "n1"}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	c, err := Parse(`curl --digest user:wrong ` + srv.URL + `/protected`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	resp, err := c.Request().Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.GetStatusCode() != http.StatusUnauthorized {
+		t.Errorf("Expected 401, got %d", resp.GetStatusCode())
+	}
+	if handler.requests != 2 {
+		t.Errorf("Expected 2 requests to server, got %d", handler.requests)
+	}
+}
+
+func TestDigestRedirectToOtherHost(t *testing.T) {
+	var leaked []string
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		leaked = append(leaked, r.Header.Get("Authorization"))
+		w.Write([]byte("other"))
+	}))
+	defer other.Close()
+
+	handler := &digestTestServer{algorithm: "MD5", qop: "auth", user: "user", pass: "secret", nonce: "n1", redirect: other.URL + "/landing"}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	c, err := Parse(`curl -L --digest user:secret ` + srv.URL + `/protected`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	// 同一个 Session 中第二次请求会预先携带认证信息，重定向到其他主机时不能继续携带
+	ses := c.CreateSession()
+	for i := 0; i < 2; i++ {
+		resp, err := c.CreateTemporary(ses).Execute()
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if resp.ContentString() != "other" {
+			t.Fatalf("unexpected body %q", resp.ContentString())
+		}
+	}
+	if len(handler.ncSeen) != 2 {
+		t.Errorf("expected 2 authorized requests to the origin, got %v", handler.ncSeen)
+	}
+	for _, value := range leaked {
+		if value != "" {
+			t.Errorf("Authorization leaked to other host: %q", value)
+		}
+	}
+}
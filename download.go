@@ -0,0 +1,175 @@
+package gcurl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/474420502/requests"
+)
+
+// Download 使用 CURL 自己创建的 Session 执行请求，并把响应体流式写入输出文件
+func (curl *CURL) Download() (*requests.Response, error) {
+	return curl.DownloadWithSession(curl.CreateSession())
+}
+
+// DownloadWithSession 执行请求，并把响应体直接流式写入 -o/-O/-J/--output-dir 指定的文件，不在内存中缓存
+// 覆盖写入时先写入同目录下的临时文件，完成后原子地重命名为目标文件
+// 返回的 Response 只包含状态和头部，Content() 为空
+func (curl *CURL) DownloadWithSession(ses *requests.Session) (*requests.Response, error) {
+	if curl.OutputFile == "" && !curl.RemoteName && !curl.RemoteHeaderName {
+		return nil, fmt.Errorf("no output file specified, use -o or -O")
+	}
+
+	var last *downloadSink
+	return curl.executeWithRetry(ses, func(req *requests.Request) (*requests.Response, error) {
+		// 与 curl 一致，只有指定了 --compressed 才把解码后的数据写入文件
+		if curl.Compressed {
+			req = req.WithMiddleware(&contentDecoder{curl: curl})
+		}
+		sink := &downloadSink{curl: curl}
+		last = sink
+		resp, err := req.WithMiddleware(sink).Execute()
+		if resp != nil && sink.header != nil {
+			resp.GetResponse().Header = sink.header
+		}
+		// 写入文件失败时仍然返回响应，调用方和 -w/-D 可以得到状态和头部
+		if err == nil {
+			err = sink.err
+		}
+		return resp, err
+	}, func() {
+		// 与 curl 一致，重试前丢弃上一次尝试追加到文件中的数据，-C - 重新按照截断后的大小续传
+		last.rollback()
+	})
+}
+
+// downloadSink 是一个 requests 中间件，在响应返回后把响应体写入文件
+type downloadSink struct {
+	curl   *CURL
+	header http.Header // 写入文件前的原始响应头
+	err    error       // 写入文件的错误，不通过中间件返回，否则 requests 会丢弃响应
+
+	appendPath string // 追加写入的文件，为空表示没有追加
+	appendSize int64  // 追加之前文件的大小
+}
+
+// rollback 把追加写入的文件截断回追加之前的大小
+func (s *downloadSink) rollback() {
+	if s.appendPath != "" {
+		os.Truncate(s.appendPath, s.appendSize)
+	}
+}
+
+// BeforeRequest 实现 requests.Middleware
+func (s *downloadSink) BeforeRequest(req *http.Request) error {
+	return nil
+}
+
+// AfterResponse 实现 requests.Middleware
+// 响应体写入文件后替换为空，requests 不会再把它读入内存
+func (s *downloadSink) AfterResponse(resp *http.Response) error {
+	c := s.curl
+	// 与 curl -f 一致，HTTP 错误时不写入文件
+	if c.FailOnError && resp.StatusCode >= 400 {
+		return nil
+	}
+
+	outputPath, err := c.outputPathFor(resp.Header)
+	if err == nil && outputPath == "" {
+		return nil
+	}
+	if err == nil {
+		s.err = s.write(resp, outputPath)
+	} else {
+		s.err = err
+	}
+	resp.Body.Close()
+	resp.Body = http.NoBody
+
+	// 响应体已经写入文件（或者因为错误被丢弃），requests 不应再按 Content-Encoding 解压空的响应体
+	s.header = hideContentEncoding(resp)
+	return nil
+}
+
+// write 按照 -C、--append 等选项把响应体写入 outputPath
+func (s *downloadSink) write(resp *http.Response, outputPath string) error {
+	c := s.curl
+	flags, write, err := c.outputOpenFlags(resp.StatusCode, resp.Header)
+	if err != nil || !write {
+		return err
+	}
+
+	var body io.Reader = resp.Body
+	if c.Include {
+		body = io.MultiReader(bytes.NewReader(formatHeaderChain(resp)), resp.Body)
+	}
+	if flags&os.O_APPEND != 0 {
+		s.appendPath = outputPath
+		if info, statErr := os.Stat(outputPath); statErr == nil {
+			s.appendSize = info.Size()
+		}
+		return c.appendBody(outputPath, body)
+	}
+	return c.replaceBody(outputPath, body)
+}
+
+// replaceBody 把 body 写入临时文件，完成后重命名为 outputPath
+// 传输中途失败时不会覆盖已经存在的目标文件；目标文件不存在时与 curl 一致保留已下载的部分，
+// 指定了 --remove-on-error 时删除临时文件
+func (c *CURL) replaceBody(outputPath string, body io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(outputPath), "."+filepath.Base(outputPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	tmpPath := tmp.Name()
+
+	_, copyErr := io.Copy(tmp, body)
+	if err := tmp.Chmod(0644); err != nil && copyErr == nil {
+		copyErr = err
+	}
+	if err := tmp.Close(); err != nil && copyErr == nil {
+		copyErr = err
+	}
+
+	if copyErr != nil {
+		if _, err := os.Stat(outputPath); c.RemoveOnError || err == nil {
+			os.Remove(tmpPath)
+			return fmt.Errorf("failed to write to file: %w", copyErr)
+		}
+	}
+	if err := os.Rename(tmpPath, outputPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	if copyErr != nil {
+		return fmt.Errorf("failed to write to file: %w", copyErr)
+	}
+	return nil
+}
+
+// appendBody 把 body 追加到 outputPath 末尾
+// 传输中途失败且指定了 --remove-on-error 时，把文件截断回追加前的大小
+func (c *CURL) appendBody(outputPath string, body io.Reader) error {
+	file, err := os.OpenFile(outputPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to stat file: %w", err)
+	}
+
+	if _, err := io.Copy(file, body); err != nil {
+		if c.RemoveOnError {
+			file.Truncate(info.Size())
+		}
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+	return nil
+}
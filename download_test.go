@@ -0,0 +1,306 @@
+package gcurl
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// downloadTestServer 提供下载测试需要的各种响应
+func downloadTestServer(t *testing.T, content []byte) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/broken":
+			// 声明的长度大于实际发送的数据，然后断开连接
+			w.Header().Set("Content-Length", "1000")
+			w.Write(content[:100])
+			w.(http.Flusher).Flush()
+			conn, _, err := w.(http.Hijacker).Hijack()
+			if err == nil {
+				conn.Close()
+			}
+		case "/attachment":
+			w.Header().Set("Content-Disposition", `attachment; filename="report.csv"`)
+			w.Write(content)
+		case "/gzip":
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			gz.Write(content)
+			gz.Close()
+		default:
+			http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// assertNoTempFiles 确认目录中没有遗留的临时文件
+func assertNoTempFiles(t *testing.T, dir string) {
+	t.Helper()
+	entries, _ := os.ReadDir(dir)
+	for _, entry := range entries {
+		if strings.HasSuffix(entry.Name(), ".tmp") {
+			t.Errorf("temp file left behind: %s", entry.Name())
+		}
+	}
+}
+
+func TestDownloadStreaming(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789abcdef"), 64*1024)
+	srv := downloadTestServer(t, content)
+
+	tests := []struct {
+		name     string
+		args     func(dir string) string
+		path     string
+		existing []byte
+		file     string
+		want     []byte
+	}{
+		{"Output file", func(dir string) string { return `-o ` + filepath.Join(dir, "out.bin") }, "/data.bin", nil, "out.bin", content},
+		{"Overwrite existing", func(dir string) string { return `-o ` + filepath.Join(dir, "out.bin") }, "/data.bin", []byte("old"), "out.bin", content},
+		{"Remote name with output dir", func(dir string) string { return `-O --output-dir ` + dir }, "/data.bin", nil, "data.bin", content},
+		{"Remote header name", func(dir string) string { return `-O -J --output-dir ` + dir }, "/attachment", nil, "report.csv", content},
+		{"Create dirs", func(dir string) string { return `--create-dirs -o ` + filepath.Join(dir, "a", "b", "out.bin") }, "/data.bin", nil, "a/b/out.bin", content},
+		{"Append", func(dir string) string { return `--append -o ` + filepath.Join(dir, "out.bin") }, "/data.bin", []byte("head:"), "out.bin", append([]byte("head:"), content...)},
+		{"Resume", func(dir string) string { return `-C - -o ` + filepath.Join(dir, "out.bin") }, "/data.bin", content[:1000], "out.bin", content},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			target := filepath.Join(dir, filepath.FromSlash(tt.file))
+			if tt.existing != nil {
+				if err := os.WriteFile(target, tt.existing, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			curl, err := Parse(`curl ` + tt.args(dir) + ` ` + srv.URL + tt.path)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			resp, err := curl.Download()
+			if err != nil {
+				t.Fatalf("Download failed: %v", err)
+			}
+			// 响应体已经写入文件，不应再缓存在内存中
+			if len(resp.Content()) != 0 {
+				t.Errorf("response body buffered: %d bytes", len(resp.Content()))
+			}
+
+			got, err := os.ReadFile(target)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.want) {
+				t.Errorf("file length = %d, want %d", len(got), len(tt.want))
+			}
+			assertNoTempFiles(t, filepath.Dir(target))
+		})
+	}
+}
+
+func TestDownloadKeepsEncodedBody(t *testing.T) {
+	content := []byte(strings.Repeat("compressible ", 100))
+	srv := downloadTestServer(t, content)
+	output := filepath.Join(t.TempDir(), "out.gz")
+
+	// 显式指定 Accept-Encoding 时与 curl 一致，保存未解压的原始数据
+	curl, err := Parse(`curl -H "Accept-Encoding: gzip" -o ` + output + ` ` + srv.URL + `/gzip`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	resp, err := curl.Download()
+	if err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	if resp.GetHeader().Get("Content-Encoding") != "gzip" {
+		t.Errorf("Content-Encoding header = %q, want gzip", resp.GetHeader().Get("Content-Encoding"))
+	}
+
+	file, err := os.Open(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatalf("saved file is not gzip: %v", err)
+	}
+	var got bytes.Buffer
+	got.ReadFrom(gz)
+	if !bytes.Equal(got.Bytes(), content) {
+		t.Errorf("decompressed content mismatch")
+	}
+}
+
+func TestDownloadFailureMidway(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1000)
+	srv := downloadTestServer(t, content)
+
+	tests := []struct {
+		name     string
+		args     string
+		existing []byte
+		want     []byte // nil 表示文件不存在
+	}{
+		{"Partial file kept", ``, nil, content[:100]},
+		{"Remove on error", `--remove-on-error`, nil, nil},
+		{"Remove on error keeps previous file", `--remove-on-error`, []byte("previous"), []byte("previous")},
+		{"Previous file not replaced by partial data", ``, []byte("previous"), []byte("previous")},
+		{"Append keeps partial data", `--append`, []byte("previous"), append([]byte("previous"), content[:100]...)},
+		{"Retries do not accumulate appended data", `--append --retry 2 --retry-delay 0.01 --retry-all-errors`, []byte("previous"), append([]byte("previous"), content[:100]...)},
+		{"Append truncated back on error", `--append --remove-on-error`, []byte("previous"), []byte("previous")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			output := filepath.Join(dir, "out.bin")
+			if tt.existing != nil {
+				if err := os.WriteFile(output, tt.existing, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			curl, err := Parse(`curl ` + tt.args + ` -o ` + output + ` ` + srv.URL + `/broken`)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			if _, err := curl.Download(); err == nil {
+				t.Fatal("Expected error for truncated transfer")
+			}
+
+			got, err := os.ReadFile(output)
+			if tt.want == nil {
+				if !os.IsNotExist(err) {
+					t.Errorf("output file should not exist, err = %v", err)
+				}
+			} else if !bytes.Equal(got, tt.want) {
+				t.Errorf("file = %q, want %q", got, tt.want)
+			}
+			assertNoTempFiles(t, dir)
+		})
+	}
+}
+
+func TestDownloadFailureKeepsResponse(t *testing.T) {
+	content := bytes.Repeat([]byte("x"), 1000)
+	srv := downloadTestServer(t, content)
+	// 不声明 Content-Length，--max-filesize 只能在写入文件时发现超限
+	chunked := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Test", "chunked")
+		w.WriteHeader(http.StatusAccepted)
+		w.(http.Flusher).Flush()
+		w.Write(content)
+	}))
+	t.Cleanup(chunked.Close)
+
+	tests := []struct {
+		name       string
+		args       string
+		wantStatus int
+	}{
+		{"Truncated transfer", srv.URL + `/broken`, http.StatusOK},
+		{"Max filesize while streaming", `--max-filesize 100 ` + chunked.URL, http.StatusAccepted},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "out.bin")
+			curl, err := Parse(`curl -w "%{http_code}" -o ` + output + ` ` + tt.args)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			var stdout bytes.Buffer
+			curl.Stdout = &stdout
+
+			resp, err := curl.Download()
+			if err == nil {
+				t.Fatal("Expected download error")
+			}
+			if resp == nil {
+				t.Fatalf("response should be returned together with the error %v", err)
+			}
+			if resp.GetStatusCode() != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.GetStatusCode(), tt.wantStatus)
+			}
+			if got := stdout.String(); got != strconv.Itoa(tt.wantStatus) {
+				t.Errorf("-w output = %q, want %d", got, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestDownloadRequiresOutput(t *testing.T) {
+	curl, err := Parse(`curl https://example.com`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := curl.Download(); err == nil {
+		t.Error("Expected error when no output file is specified")
+	}
+}
+
+func TestAppendOption(t *testing.T) {
+	for _, cmd := range []string{`curl -a -o out https://example.com`, `curl --append -o out https://example.com`} {
+		curl, err := Parse(cmd)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if !curl.Append {
+			t.Errorf("Append should be true for %s", cmd)
+		}
+	}
+}
+
+func TestDownloadResumeRetry(t *testing.T) {
+	content := bytes.Repeat([]byte("0123456789"), 100)
+	var ranges []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ranges = append(ranges, r.Header.Get("Range"))
+		if len(ranges) == 1 {
+			// 第一次只发送一部分数据就断开连接
+			w.Header().Set("Content-Range", fmt.Sprintf("bytes 10-%d/%d", len(content)-1, len(content)))
+			w.Header().Set("Content-Length", strconv.Itoa(len(content)-10))
+			w.WriteHeader(http.StatusPartialContent)
+			w.Write(content[10:60])
+			w.(http.Flusher).Flush()
+			if conn, _, err := w.(http.Hijacker).Hijack(); err == nil {
+				conn.Close()
+			}
+			return
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	output := filepath.Join(t.TempDir(), "out.bin")
+	if err := os.WriteFile(output, content[:10], 0644); err != nil {
+		t.Fatal(err)
+	}
+	curl, err := Parse(`curl -C - --retry 1 --retry-delay 0.01 --retry-all-errors -o ` + output + ` ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := curl.Download(); err != nil {
+		t.Fatalf("Download failed: %v", err)
+	}
+	// 重试前截断回续传开始时的大小，因此两次都从第 10 个字节开始
+	if len(ranges) != 2 || ranges[0] != "bytes=10-" || ranges[1] != "bytes=10-" {
+		t.Errorf("Range headers = %q", ranges)
+	}
+	if got, _ := os.ReadFile(output); !bytes.Equal(got, content) {
+		t.Errorf("file length = %d, want %d", len(got), len(content))
+	}
+}
@@ -0,0 +1,92 @@
+package gcurl
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/474420502/requests"
+)
+
+// ExitCodeFileSizeExceeded 与 curl 超过 --max-filesize 时的退出码一致
+const ExitCodeFileSizeExceeded = 63
+
+// MaxFileSizeError 表示响应体超过了 --max-filesize 的限制
+// 可以通过 errors.As 从 Execute 返回的错误中取出
+type MaxFileSizeError struct {
+	Limit int64 // --max-filesize 指定的字节数
+	Size  int64 // 服务端声明的 Content-Length，流式读取时超出为 -1
+}
+
+// Error 实现 error
+func (e *MaxFileSizeError) Error() string {
+	if e.Size >= 0 {
+		return fmt.Sprintf("maximum file size exceeded: Content-Length %d exceeds limit of %d bytes", e.Size, e.Limit)
+	}
+	return fmt.Sprintf("maximum file size exceeded: body exceeds limit of %d bytes", e.Limit)
+}
+
+// ExitCode 返回对应的 curl 退出码
+func (e *MaxFileSizeError) ExitCode() int {
+	return ExitCodeFileSizeExceeded
+}
+
+// maxFileSizeReader 在读取的数据超过限制时返回 MaxFileSizeError
+type maxFileSizeReader struct {
+	reader io.ReadCloser
+	limit  int64
+	read   int64
+}
+
+// Read 实现 io.Reader
+func (r *maxFileSizeReader) Read(p []byte) (int, error) {
+	// 多读一个字节，以便区分“恰好等于限制”和“超过限制”
+	if remain := r.limit - r.read + 1; int64(len(p)) > remain {
+		p = p[:remain]
+	}
+	n, err := r.reader.Read(p)
+	r.read += int64(n)
+	if r.read > r.limit {
+		return n, &MaxFileSizeError{Limit: r.limit, Size: -1}
+	}
+	return n, err
+}
+
+// Close 实现 io.Closer
+func (r *maxFileSizeReader) Close() error {
+	return r.reader.Close()
+}
+
+// maxFileSizeTransport 在响应头声明的大小超过限制时立即中止，否则在读取响应体时计数
+type maxFileSizeTransport struct {
+	base  http.RoundTripper
+	limit int64
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *maxFileSizeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	// HEAD 请求没有响应体，Content-Length 只是描述信息
+	if req.Method == http.MethodHead {
+		return resp, nil
+	}
+	if resp.ContentLength > t.limit {
+		resp.Body.Close()
+		return nil, &MaxFileSizeError{Limit: t.limit, Size: resp.ContentLength}
+	}
+	resp.Body = &maxFileSizeReader{reader: resp.Body, limit: t.limit}
+	return resp, nil
+}
+
+// configureMaxFileSize 为 --max-filesize 包装 Transport
+func (curl *CURL) configureMaxFileSize(ses *requests.Session) {
+	if curl.MaxFileSize <= 0 {
+		return
+	}
+	if client := sessionClient(ses); client != nil {
+		client.Transport = &maxFileSizeTransport{base: client.Transport, limit: curl.MaxFileSize}
+	}
+}
@@ -0,0 +1,105 @@
+package gcurl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMaxFileSizeParsing(t *testing.T) {
+	tests := []struct {
+		cmd     string
+		want    int64
+		wantErr bool
+	}{
+		{`curl --max-filesize 1000 https://example.com`, 1000, false},
+		{`curl --max-filesize 10K https://example.com`, 10 * 1024, false},
+		{`curl --max-filesize 2M https://example.com`, 2 * 1024 * 1024, false},
+		{`curl --max-filesize huge https://example.com`, 0, true},
+		{`curl --max-filesize -5 https://example.com`, 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.cmd, func(t *testing.T) {
+			curl, err := Parse(tt.cmd)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Parse error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && curl.MaxFileSize != tt.want {
+				t.Errorf("MaxFileSize = %d, want %d", curl.MaxFileSize, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaxFileSizeExecution(t *testing.T) {
+	body := strings.Repeat("x", 2048)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/stream":
+			// 不声明 Content-Length，先发送超过限制的数据，然后一直挂起
+			w.Write([]byte(body))
+			w.(http.Flusher).Flush()
+			select {
+			case <-r.Context().Done():
+			case <-time.After(5 * time.Second):
+			}
+		default:
+			w.Write([]byte(body))
+		}
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name     string
+		args     string
+		path     string
+		wantErr  bool
+		wantSize int64
+	}{
+		{"Content-Length over limit", `--max-filesize 1K`, "/", true, 2048},
+		{"Streamed body over limit", `--max-filesize 1K`, "/stream", true, -1},
+		{"Exactly at limit", `--max-filesize 2K`, "/", false, 0},
+		{"HEAD not limited", `-I --max-filesize 1K`, "/", false, 0},
+		{"No limit", ``, "/", false, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curl, err := Parse(`curl ` + tt.args + ` ` + srv.URL + tt.path)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			start := time.Now()
+			resp, err := curl.Execute()
+			if !tt.wantErr {
+				if err != nil {
+					t.Fatalf("Execute failed: %v", err)
+				}
+				if resp.GetStatusCode() != 200 {
+					t.Errorf("status = %d, want 200", resp.GetStatusCode())
+				}
+				return
+			}
+
+			var sizeErr *MaxFileSizeError
+			if !errors.As(err, &sizeErr) {
+				t.Fatalf("Expected MaxFileSizeError, got %v", err)
+			}
+			if sizeErr.ExitCode() != ExitCodeFileSizeExceeded {
+				t.Errorf("ExitCode = %d, want %d", sizeErr.ExitCode(), ExitCodeFileSizeExceeded)
+			}
+			if sizeErr.Limit != 1024 || sizeErr.Size != tt.wantSize {
+				t.Errorf("error = %+v, want Limit 1024 Size %d", sizeErr, tt.wantSize)
+			}
+			// 超过限制后应立即中止，而不是等待服务端发送完毕
+			if elapsed := time.Since(start); elapsed > 2*time.Second {
+				t.Errorf("transfer not aborted early, elapsed %v", elapsed)
+			}
+		})
+	}
+}
@@ -1,6 +1,9 @@
 package gcurl
 
 import (
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"strings"
 	"testing"
@@ -246,3 +249,128 @@ func TestGetModeVerboseOutput(t *testing.T) {
 		t.Error("Verbose output should contain the URL")
 	}
 }
+
+// TestGetModeQueryConversion 测试 -G 将数据转换为查询参数并清空请求体
+func TestGetModeQueryConversion(t *testing.T) {
+	testCases := []struct {
+		name      string
+		command   string
+		expectURL string
+	}{
+		{
+			name:      "Multiple data options",
+			command:   `curl -G -d "param1=value1" -d "param2=value2" https://example.com/api`,
+			expectURL: "https://example.com/api?param1=value1&param2=value2",
+		},
+		{
+			name:      "Existing query preserved",
+			command:   `curl -G -d "new=param" "https://example.com/api?existing=value"`,
+			expectURL: "https://example.com/api?existing=value&new=param",
+		},
+		{
+			name:      "Order preserved",
+			command:   `curl --get -d "user=john&age=30" https://example.com/search`,
+			expectURL: "https://example.com/search?user=john&age=30",
+		},
+		{
+			name:      "Data appended unchanged",
+			command:   `curl -G -d "path=/a/b" -d "q=hello%20world" -d "rate=50%" https://example.com/search`,
+			expectURL: "https://example.com/search?path=/a/b&q=hello%20world&rate=50%",
+		},
+		{
+			name:      "Spaces escaped",
+			command:   `curl -G -d "query=hello world" -d "tag=a#b" https://example.com/search`,
+			expectURL: "https://example.com/search?query=hello%20world&tag=a%23b",
+		},
+		{
+			name:      "Brackets not encoded",
+			command:   `curl -G -d "filters[status]=active" -d "filters[type]=user" https://example.com/api`,
+			expectURL: "https://example.com/api?filters[status]=active&filters[type]=user",
+		},
+		{
+			name:      "Data urlencode not double encoded",
+			command:   `curl -G --data-urlencode "q=a&b c" https://example.com/search`,
+			expectURL: "https://example.com/search?q=a%26b+c",
+		},
+		{
+			name:      "Data before -G",
+			command:   `curl -d "a=1" -G https://example.com/api`,
+			expectURL: "https://example.com/api?a=1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			curl, err := Parse(tc.command)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			if curl.ParsedURL.String() != tc.expectURL {
+				t.Errorf("Expected URL %s, got %s", tc.expectURL, curl.ParsedURL.String())
+			}
+			if curl.Method != "GET" {
+				t.Errorf("Expected method GET, got %s", curl.Method)
+			}
+			if curl.Body.Len() != 0 {
+				t.Errorf("Expected empty body, got %q", curl.Body.String())
+			}
+			if curl.Header.Get("Content-Type") != "" {
+				t.Errorf("Expected no Content-Type, got %s", curl.Header.Get("Content-Type"))
+			}
+		})
+	}
+}
+
+// TestGetModeExecution 测试 -G 的请求在服务端收到的是查询参数而不是请求体
+func TestGetModeExecution(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("X-Method", r.Method)
+		w.Header().Set("X-Query", r.URL.RawQuery)
+		w.Header().Set("X-Body", string(body))
+	}))
+	defer srv.Close()
+
+	testCases := []struct {
+		name         string
+		command      string
+		expectMethod string
+		expectQuery  string
+	}{
+		{name: "GET", command: `curl -G -d "q=x" ` + srv.URL + `/search?lang=go`, expectMethod: "GET", expectQuery: "lang=go&q=x"},
+		{name: "HEAD", command: `curl -I -G -d "q=x" ` + srv.URL + `/search?lang=go`, expectMethod: "HEAD", expectQuery: "lang=go&q=x"},
+		{name: "HEAD after -G", command: `curl -G -d "q=x" -I ` + srv.URL + `/search?lang=go`, expectMethod: "HEAD", expectQuery: "lang=go&q=x"},
+		{
+			name:         "Spaces escaped, encoded data kept",
+			command:      `curl -G -d "q=hello world" -d "path=/a/b" -d "rate=50%25" ` + srv.URL + `/search?lang=go`,
+			expectMethod: "GET",
+			expectQuery:  "lang=go&q=hello%20world&path=/a/b&rate=50%25",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			curl, err := Parse(tc.command)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			resp, err := curl.Request().Execute()
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			header := resp.GetHeader()
+			if header.Get("X-Method") != tc.expectMethod {
+				t.Errorf("Expected method %s, got %s", tc.expectMethod, header.Get("X-Method"))
+			}
+			if header.Get("X-Query") != tc.expectQuery {
+				t.Errorf("Expected query %s, got %s", tc.expectQuery, header.Get("X-Query"))
+			}
+			if header.Get("X-Body") != "" {
+				t.Errorf("Expected empty body, got %s", header.Get("X-Body"))
+			}
+		})
+	}
+}
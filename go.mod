@@ -1,14 +1,18 @@
 module github.com/474420502/gcurl
 
-go 1.20
+go 1.22
 
-require github.com/474420502/requests v1.50.0
+require (
+	github.com/474420502/requests v1.50.0
+	github.com/andybalholm/brotli v1.0.5
+	github.com/klauspost/compress v1.18.0
+	golang.org/x/net v0.12.0
+)
 
 require (
-	github.com/andybalholm/brotli v1.0.5 // indirect
 	github.com/schollz/progressbar v1.0.0 // indirect
 	github.com/tidwall/gjson v1.12.0 // indirect
 	github.com/tidwall/match v1.1.1 // indirect
 	github.com/tidwall/pretty v1.2.0 // indirect
-	golang.org/x/net v0.12.0 // indirect
+	golang.org/x/text v0.11.0 // indirect
 )
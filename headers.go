@@ -0,0 +1,113 @@
+package gcurl
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/474420502/requests"
+)
+
+// stdout 返回标准输出的目标
+func (c *CURL) stdout() io.Writer {
+	if c.Stdout != nil {
+		return c.Stdout
+	}
+	return os.Stdout
+}
+
+// stdin 返回标准输入的来源
+func (c *CURL) stdin() io.Reader {
+	if c.Stdin != nil {
+		return c.Stdin
+	}
+	return os.Stdin
+}
+
+// stderr 返回标准错误的目标
+func (c *CURL) stderr() io.Writer {
+	if c.Stderr != nil {
+		return c.Stderr
+	}
+	return os.Stderr
+}
+
+// openDumpHeader 打开 -D 指定的目标，未指定时返回 nil
+// 与 curl 一致，每次执行都会重新创建文件
+func (c *CURL) openDumpHeader() (io.WriteCloser, error) {
+	switch c.DumpHeader {
+	case "":
+		return nil, nil
+	case "-":
+		return nopWriteCloser{c.stdout()}, nil
+	}
+	file, err := os.Create(c.DumpHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create dump header file: %w", err)
+	}
+	return file, nil
+}
+
+// nopWriteCloser 为 io.Writer 提供一个空的 Close
+type nopWriteCloser struct {
+	io.Writer
+}
+
+// Close 实现 io.Closer
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// statusLine 返回 curl 格式的状态行（不含换行）
+func statusLine(resp *http.Response) string {
+	if resp.ProtoMajor == 2 {
+		// curl 对 HTTP/2 只输出状态码，并保留末尾的空格
+		return fmt.Sprintf("HTTP/2 %03d ", resp.StatusCode)
+	}
+	return fmt.Sprintf("%s %s", resp.Proto, resp.Status)
+}
+
+// formatResponseHeaders 按照 curl -D/-i 的格式输出一个响应的状态行和头部，以空行结束
+func formatResponseHeaders(resp *http.Response) []byte {
+	var b bytes.Buffer
+	b.WriteString(statusLine(resp))
+	b.WriteString("\r\n")
+	resp.Header.Write(&b)
+	// Go 把 Transfer-Encoding 从头部中移到了单独的字段
+	if len(resp.TransferEncoding) > 0 && resp.Header.Get("Transfer-Encoding") == "" {
+		for _, te := range resp.TransferEncoding {
+			fmt.Fprintf(&b, "Transfer-Encoding: %s\r\n", te)
+		}
+	}
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+// formatHeaderChain 输出重定向链上每个响应的头部，最终响应在最后
+func formatHeaderChain(resp *http.Response) []byte {
+	var b bytes.Buffer
+	for _, r := range redirectChain(resp) {
+		b.Write(formatResponseHeaders(r))
+	}
+	return b.Bytes()
+}
+
+// includeHeaders 返回一个新的 Response，其内容是所有响应头加上原来的响应体，用于 -i
+func includeHeaders(resp *requests.Response) (*requests.Response, error) {
+	raw := resp.GetResponse()
+	content := append(formatHeaderChain(raw), resp.Content()...)
+
+	// 原响应体已经解压，构造新 Response 时不能再按 Content-Encoding 解压
+	dup := *raw
+	dup.Header = raw.Header.Clone()
+	dup.Header.Del("Content-Encoding")
+	dup.Body = io.NopCloser(bytes.NewReader(content))
+	included, err := requests.FromHTTPResponse(&dup, false)
+	if err != nil {
+		return nil, err
+	}
+	included.GetResponse().Header = raw.Header
+	return included, nil
+}
@@ -0,0 +1,186 @@
+package gcurl
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// headerTestServer 在 /redirect 返回 302，在 /final 返回带自定义头的 200
+func headerTestServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		default:
+			w.Header().Set("Content-Type", "text/plain")
+			w.Header().Set("X-Test", "value")
+			w.Header()["Date"] = nil
+			w.Write([]byte("body"))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDumpHeaderParsing(t *testing.T) {
+	for _, cmd := range []string{`curl -D headers.txt https://example.com`, `curl --dump-header headers.txt https://example.com`} {
+		curl, err := Parse(cmd)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if curl.DumpHeader != "headers.txt" {
+			t.Errorf("DumpHeader = %q, want headers.txt", curl.DumpHeader)
+		}
+	}
+}
+
+func TestDumpHeaderToFile(t *testing.T) {
+	srv := headerTestServer(t)
+	dumpFile := filepath.Join(t.TempDir(), "headers.txt")
+
+	curl, err := Parse(`curl -L -D ` + dumpFile + ` ` + srv.URL + `/redirect`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	resp, err := curl.Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.ContentString() != "body" {
+		t.Errorf("body = %q, want body", resp.ContentString())
+	}
+
+	dump, err := os.ReadFile(dumpFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	blocks := strings.Split(strings.TrimSuffix(string(dump), "\r\n\r\n"), "\r\n\r\n")
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 header blocks, got %d:\n%s", len(blocks), dump)
+	}
+	if !strings.HasPrefix(blocks[0], "HTTP/1.1 302 Found\r\n") || !strings.Contains(blocks[0], "\r\nLocation: /final") {
+		t.Errorf("unexpected redirect block:\n%s", blocks[0])
+	}
+	want := "HTTP/1.1 200 OK\r\nContent-Length: 4\r\nContent-Type: text/plain\r\nX-Test: value"
+	if blocks[1] != want {
+		t.Errorf("final block = %q, want %q", blocks[1], want)
+	}
+}
+
+func TestDumpHeaderToStdout(t *testing.T) {
+	srv := headerTestServer(t)
+
+	var out bytes.Buffer
+	curl, err := Parse(`curl -D - ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	curl.Stdout = &out
+	if _, err := curl.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if !strings.HasPrefix(out.String(), "HTTP/1.1 200 OK\r\n") || !strings.HasSuffix(out.String(), "X-Test: value\r\n\r\n") {
+		t.Errorf("unexpected header dump: %q", out.String())
+	}
+}
+
+func TestIncludeHeaders(t *testing.T) {
+	srv := headerTestServer(t)
+
+	t.Run("Returned to caller", func(t *testing.T) {
+		curl, err := Parse(`curl -i -L ` + srv.URL + `/redirect`)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		resp, err := curl.Execute()
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		content := resp.ContentString()
+		if !strings.HasPrefix(content, "HTTP/1.1 302 Found\r\n") {
+			t.Errorf("content should start with redirect headers: %q", content)
+		}
+		if !strings.HasSuffix(content, "X-Test: value\r\n\r\nbody") {
+			t.Errorf("content should end with final headers and body: %q", content)
+		}
+		// 响应头本身不受影响
+		if resp.GetStatusCode() != 200 || resp.GetHeader().Get("X-Test") != "value" {
+			t.Errorf("response metadata changed: %d %v", resp.GetStatusCode(), resp.GetHeader())
+		}
+	})
+
+	t.Run("Execution error kept", func(t *testing.T) {
+		curl, err := Parse(`curl -i -w '%{http_code}' ` + srv.URL)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		curl.Stdout = failingWriter{}
+		resp, err := curl.Execute()
+		if err == nil {
+			t.Fatal("expected the -w write error to be returned")
+		}
+		if resp == nil || !strings.HasPrefix(resp.ContentString(), "HTTP/1.1 200 OK\r\n") {
+			t.Errorf("included response should still be returned")
+		}
+	})
+
+	t.Run("Written to output file", func(t *testing.T) {
+		output := filepath.Join(t.TempDir(), "out.txt")
+		curl, err := Parse(`curl -i -o ` + output + ` ` + srv.URL)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if _, err := curl.Download(); err != nil {
+			t.Fatalf("Download failed: %v", err)
+		}
+		got, err := os.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := "HTTP/1.1 200 OK\r\nContent-Length: 4\r\nContent-Type: text/plain\r\nX-Test: value\r\n\r\nbody"
+		if string(got) != want {
+			t.Errorf("file = %q, want %q", got, want)
+		}
+	})
+}
+
+func TestFormatResponseHeaders(t *testing.T) {
+	resp := &http.Response{
+		Proto:            "HTTP/2.0",
+		ProtoMajor:       2,
+		StatusCode:       204,
+		Status:           "204 No Content",
+		Header:           http.Header{"B": {"2"}, "A": {"1", "3"}},
+		TransferEncoding: nil,
+	}
+	want := "HTTP/2 204 \r\nA: 1\r\nA: 3\r\nB: 2\r\n\r\n"
+	if got := string(formatResponseHeaders(resp)); got != want {
+		t.Errorf("formatResponseHeaders = %q, want %q", got, want)
+	}
+
+	resp = &http.Response{
+		Proto:            "HTTP/1.1",
+		ProtoMajor:       1,
+		StatusCode:       200,
+		Status:           "200 OK",
+		Header:           http.Header{},
+		TransferEncoding: []string{"chunked"},
+	}
+	want = "HTTP/1.1 200 OK\r\nTransfer-Encoding: chunked\r\n\r\n"
+	if got := string(formatResponseHeaders(resp)); got != want {
+		t.Errorf("formatResponseHeaders = %q, want %q", got, want)
+	}
+}
+
+// failingWriter 的每次写入都失败
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("write failed")
+}
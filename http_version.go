@@ -0,0 +1,253 @@
+package gcurl
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+
+	"github.com/474420502/requests"
+	"golang.org/x/net/http2"
+)
+
+// configureHTTPVersion 配置HTTP协议版本
+// 协商到的协议可以通过响应的 GetResponse().Proto 查看
+func (curl *CURL) configureHTTPVersion(ses *requests.Session) {
+	transport := sessionTransport(ses)
+	if transport == nil {
+		return
+	}
+
+	switch curl.httpVersion() {
+	case HTTPVersion10:
+		// 强制使用 HTTP/1.0：改写请求行，并且每个连接只发送一个请求
+		transport.DisableKeepAlives = true
+		disableHTTP2(transport)
+		dial := baseDialContext(transport)
+		transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dial(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &http10Conn{Conn: conn}, nil
+		}
+		// TLS 连接需要在握手之后再改写请求行，因此由我们自己完成握手，ALPN 只提供 http/1.1
+		// 已经有自定义的 TLS 拨号函数（例如 --trace）时在它的基础上改写
+		dialTLSConn := transport.DialTLSContext
+		if dialTLSConn == nil {
+			dialTLSConn = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return dialTLS(ctx, transport, dial, network, addr)
+			}
+		}
+		transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+			conn, err := dialTLSConn(ctx, network, addr)
+			if err != nil {
+				return nil, err
+			}
+			return &http10Conn{Conn: conn}, nil
+		}
+	case HTTPVersion11:
+		// 强制使用 HTTP/1.1：ALPN 只提供 http/1.1
+		disableHTTP2(transport)
+	case HTTPVersion2:
+		// 强制使用 HTTP/2：TLS 连接必须通过 ALPN 协商出 h2，明文连接仍然使用 HTTP/1.1
+		requireHTTP2(transport)
+		if transport.DialTLSContext != nil {
+			wrapHTTP2Transport(ses, transport, false)
+		}
+	case HTTPVersion2PriorKnowledge:
+		// HTTP/2 prior knowledge：明文连接直接发送 h2c，TLS 连接必须通过 ALPN 协商出 h2
+		requireHTTP2(transport)
+		wrapHTTP2Transport(ses, transport, true)
+	}
+	// HTTPVersionAuto：TLS 连接通过 ALPN 协商，见 configureTransport
+}
+
+// httpVersion 返回实际使用的协议版本，兼容只设置了 HTTP2 字段的调用方
+func (curl *CURL) httpVersion() HTTPVersion {
+	if curl.HTTPVersion == HTTPVersionAuto && curl.HTTP2 {
+		return HTTPVersion2
+	}
+	return curl.HTTPVersion
+}
+
+// requireHTTP2 把 ALPN 限制为 h2，服务端没有选择 h2 时握手失败，而不是静默回退到 HTTP/1.1
+// net/http 在 ForceAttemptHTTP2 时仍会追加 http/1.1，因此还需要在握手时检查协商结果
+func requireHTTP2(transport *http.Transport) {
+	transport.ForceAttemptHTTP2 = true
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	config := transport.TLSClientConfig
+	config.NextProtos = []string{"h2"}
+	config.VerifyConnection = chainVerifyConnection(config.VerifyConnection, func(state tls.ConnectionState) error {
+		if state.NegotiatedProtocol != "h2" {
+			return fmt.Errorf("HTTP/2 was requested but the server selected %q via ALPN", state.NegotiatedProtocol)
+		}
+		return nil
+	})
+}
+
+// disableHTTP2 禁止 Transport 升级到 HTTP/2，并把 ALPN 限制为 http/1.1
+func disableHTTP2(transport *http.Transport) {
+	transport.ForceAttemptHTTP2 = false
+	// 非 nil 的空 map 会阻止 net/http 自动启用 HTTP/2
+	transport.TLSNextProto = map[string]func(string, *tls.Conn) http.RoundTripper{}
+	if transport.TLSClientConfig == nil {
+		transport.TLSClientConfig = &tls.Config{}
+	}
+	transport.TLSClientConfig.NextProtos = []string{"http/1.1"}
+}
+
+// baseDialContext 返回 Transport 当前使用的拨号函数
+func baseDialContext(transport *http.Transport) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if transport.DialContext != nil {
+		return transport.DialContext
+	}
+	return (&net.Dialer{}).DialContext
+}
+
+// dialTLS 使用 Transport 的 TLS 配置建立 TLS 连接，SNI 使用原始的主机名
+func dialTLS(ctx context.Context, transport *http.Transport, dial func(ctx context.Context, network, addr string) (net.Conn, error), network, addr string) (*tls.Conn, error) {
+	conn, err := dial(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{}
+	if transport.TLSClientConfig != nil {
+		config = transport.TLSClientConfig.Clone()
+	}
+	if config.ServerName == "" {
+		host, _, err := net.SplitHostPort(addr)
+		if err != nil {
+			host = addr
+		}
+		config.ServerName = host
+	}
+
+	if transport.TLSHandshakeTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, transport.TLSHandshakeTimeout)
+		defer cancel()
+	}
+
+	// 返回的连接会被包装，net/http 不会再触发 TLS 相关的 httptrace 事件，这里代为触发
+	trace := httptrace.ContextClientTrace(ctx)
+	if trace != nil && trace.TLSHandshakeStart != nil {
+		trace.TLSHandshakeStart()
+	}
+	tlsConn := tls.Client(conn, config)
+	err = tlsConn.HandshakeContext(ctx)
+	if trace != nil && trace.TLSHandshakeDone != nil {
+		trace.TLSHandshakeDone(tlsConn.ConnectionState(), err)
+	}
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// http10Conn 将连接上发出的第一个请求行从 HTTP/1.1 改写为 HTTP/1.0
+// net/http 总是发送 HTTP/1.1 请求行，只能在写入连接时改写
+type http10Conn struct {
+	net.Conn
+	pending []byte
+	done    bool
+}
+
+// maxRequestLine 请求行的最大缓冲长度，超过后不再尝试改写
+const maxRequestLine = 64 * 1024
+
+// Write 缓冲数据直到读到完整的请求行，改写后再一并写出
+func (c *http10Conn) Write(p []byte) (int, error) {
+	if c.done {
+		return c.Conn.Write(p)
+	}
+
+	// 不是以请求方法开头的数据（例如 TLS 握手）直接透传
+	if len(c.pending) == 0 && len(p) > 0 && (p[0] < 'A' || p[0] > 'Z') {
+		c.done = true
+		return c.Conn.Write(p)
+	}
+
+	c.pending = append(c.pending, p...)
+	end := bytes.Index(c.pending, []byte("\r\n"))
+	if end < 0 && len(c.pending) < maxRequestLine {
+		return len(p), nil
+	}
+
+	// CONNECT 隧道请求保持原样，隧道内的数据不再改写
+	if end >= 0 && !bytes.HasPrefix(c.pending, []byte("CONNECT ")) && bytes.HasSuffix(c.pending[:end], []byte(" HTTP/1.1")) {
+		copy(c.pending[end-len("1.1"):end], "1.0")
+	}
+
+	c.done = true
+	data := c.pending
+	c.pending = nil
+	if _, err := c.Conn.Write(data); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+// http2Transport 使用 x/net/http2 直接发送 HTTP/2 请求，其他请求交给原 Transport
+//   - h2c 不为 nil 时 http:// 请求使用 HTTP/2 prior knowledge
+//   - h2 不为 nil 时 https:// 请求使用 h2。Transport 有自定义的 TLS 拨号函数（例如 --trace）时，
+//     net/http 拿不到 *tls.Conn，不会在这样的连接上使用 HTTP/2
+type http2Transport struct {
+	base *http.Transport
+	h2c  *http2.Transport
+	h2   *http2.Transport
+}
+
+// wrapHTTP2Transport 在 Session 的 Transport 外层包装 http2Transport
+// priorKnowledge 为 true 时明文请求使用 h2c；base 有自定义的 TLS 拨号函数时 TLS 请求使用 h2
+func wrapHTTP2Transport(ses *requests.Session, base *http.Transport, priorKnowledge bool) {
+	client := sessionClient(ses)
+	if client == nil {
+		return
+	}
+	// client.Transport 不是 *http.Transport 时说明已经包装过（或者配置出错），不再重复包装
+	if _, ok := client.Transport.(*http.Transport); !ok {
+		return
+	}
+
+	t := &http2Transport{base: base}
+	if priorKnowledge {
+		dial := baseDialContext(base)
+		t.h2c = &http2.Transport{
+			AllowHTTP:          true,
+			DisableCompression: base.DisableCompression,
+			// AllowHTTP 时 http2 仍然调用 DialTLSContext，这里直接建立明文连接
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dial(ctx, network, addr)
+			},
+		}
+	}
+	if dialTLSConn := base.DialTLSContext; dialTLSConn != nil {
+		t.h2 = &http2.Transport{
+			DisableCompression: base.DisableCompression,
+			// TLS 配置（包括只提供 h2 的 ALPN）由 base 的拨号函数使用
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				return dialTLSConn(ctx, network, addr)
+			},
+		}
+	}
+	client.Transport = t
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *http2Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	switch {
+	case req.URL.Scheme == "http" && t.h2c != nil:
+		return t.h2c.RoundTrip(req)
+	case req.URL.Scheme == "https" && t.h2 != nil:
+		return t.h2.RoundTrip(req)
+	}
+	return t.base.RoundTrip(req)
+}
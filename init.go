@@ -1,6 +1,7 @@
 package gcurl
 
 import (
+	"bytes"
 	"compress/flate"
 	"compress/gzip"
 	"io"
@@ -8,6 +9,9 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
 )
 
 // testServer 使用 sync.Once 确保线程安全的单例模式
@@ -21,29 +25,54 @@ func getTestServer() *http.ServeMux {
 	testServerOnce.Do(func() {
 		testServer = http.NewServeMux()
 		testServer.HandleFunc("/get/body-compressed", func(w http.ResponseWriter, r *http.Request) {
-			var writer io.Writer = w
-
-			encodings := r.Header.Get("Accept-Encoding")
-			if strings.Contains(encodings, "gzip") {
-				w.Header().Set("Content-Encoding", "gzip")
-				writer = gzip.NewWriter(writer)
-				writer.Write([]byte("hello compress"))
-				defer writer.(*gzip.Writer).Close()
-			} else if strings.Contains(encodings, "deflate") {
-				w.Header().Set("Content-Encoding", "deflate")
-				writer, err := flate.NewWriter(writer, flate.DefaultCompression)
-				if err != nil {
-					panic(err)
+			// ?encoding=gzip,br 按顺序叠加多个编码，否则按照 Accept-Encoding 选择一种
+			var encodings []string
+			if forced := r.URL.Query().Get("encoding"); forced != "" {
+				encodings = strings.Split(forced, ",")
+			} else {
+				accepted := r.Header.Get("Accept-Encoding")
+				for _, encoding := range []string{"zstd", "br", "gzip", "deflate"} {
+					if strings.Contains(accepted, encoding) {
+						encodings = []string{encoding}
+						break
+					}
 				}
-				writer.Write([]byte("hello compress"))
-				defer writer.Close()
 			}
-			// Use r.Body to read uncompressed request body
+
+			body := []byte("hello compress")
+			for _, encoding := range encodings {
+				body = compressTestBody(encoding, body)
+			}
+			if len(encodings) > 0 {
+				w.Header().Set("Content-Encoding", strings.Join(encodings, ", "))
+			}
+			w.Write(body)
 		})
 	})
 	return testServer
 }
 
+// compressTestBody 使用指定的编码压缩测试数据，deflate 使用不带 zlib 头的原始格式
+func compressTestBody(encoding string, data []byte) []byte {
+	var buf bytes.Buffer
+	var writer io.WriteCloser
+	switch encoding {
+	case "gzip":
+		writer = gzip.NewWriter(&buf)
+	case "deflate":
+		writer, _ = flate.NewWriter(&buf, flate.DefaultCompression)
+	case "br":
+		writer = brotli.NewWriter(&buf)
+	case "zstd":
+		writer, _ = zstd.NewWriter(&buf)
+	default:
+		return data
+	}
+	writer.Write(data)
+	writer.Close()
+	return buf.Bytes()
+}
+
 func init() {
 	log.SetFlags(log.Llongfile)
 }
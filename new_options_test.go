@@ -66,8 +66,17 @@ func TestNewOptions(t *testing.T) {
 			description: "测试无效的最大重定向次数",
 		},
 		{
-			name:        "Negative max-redirs",
+			name:        "Unlimited max-redirs",
 			curlCmd:     `curl --max-redirs -1 https://httpbin.org/get`,
+			expectError: false,
+			checkFunc: func(c *CURL) bool {
+				return c.MaxRedirs == -1
+			},
+			description: "测试 -1 表示不限制重定向次数",
+		},
+		{
+			name:        "Negative max-redirs",
+			curlCmd:     `curl --max-redirs -2 https://httpbin.org/get`,
 			expectError: true,
 			checkFunc:   nil,
 			description: "测试负数最大重定向次数",
@@ -3,6 +3,8 @@ package gcurl
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"net/http"
 	"net/url"
 	"os"
 	"strconv"
@@ -66,12 +68,24 @@ func init() {
 	}
 	optionRegistry["--compressed"] = compressedSpec
 
+	// --raw (不解码响应体)
+	rawSpec := OptionSpec{Handler: handleRaw, NumArgs: 0}
+	optionRegistry["--raw"] = rawSpec
+
 	socks5Spec := OptionSpec{
 		Handler: handleSocks5,
 		NumArgs: 1, // --socks5 需要一个参数（代理服务器地址）
 	}
 	optionRegistry["--socks5"] = socks5Spec
 
+	// --socks4 / --socks4a / --socks5-hostname (本地或代理端解析主机名的 SOCKS 代理)
+	socks4Spec := OptionSpec{Handler: handleSocks4, NumArgs: 1}
+	optionRegistry["--socks4"] = socks4Spec
+	socks4aSpec := OptionSpec{Handler: handleSocks4a, NumArgs: 1}
+	optionRegistry["--socks4a"] = socks4aSpec
+	socks5HostnameSpec := OptionSpec{Handler: handleSocks5Hostname, NumArgs: 1}
+	optionRegistry["--socks5-hostname"] = socks5HostnameSpec
+
 	connectTimeoutSpec := OptionSpec{
 		Handler: handleConnectTimeout,
 		NumArgs: 1, // 需要一个参数
@@ -110,6 +124,10 @@ func init() {
 	limitRateSpec := OptionSpec{Handler: handleLimitRate, NumArgs: 1}
 	optionRegistry["--limit-rate"] = limitRateSpec
 
+	// --max-filesize
+	maxFileSizeSpec := OptionSpec{Handler: handleMaxFileSize, NumArgs: 1}
+	optionRegistry["--max-filesize"] = maxFileSizeSpec
+
 	// --cookie / -b
 	cookieSpec := OptionSpec{Handler: handleCookie, NumArgs: 1}
 	optionRegistry["-b"] = cookieSpec
@@ -130,8 +148,33 @@ func init() {
 	optionRegistry["-L"] = locationSpec
 	optionRegistry["--location"] = locationSpec
 
-	// --max-time (最大执行时间)
+	// --retry 系列选项 (失败重试)
+	retrySpec := OptionSpec{Handler: handleRetry, NumArgs: 1}
+	optionRegistry["--retry"] = retrySpec
+	retryDelaySpec := OptionSpec{Handler: handleRetryDelay, NumArgs: 1}
+	optionRegistry["--retry-delay"] = retryDelaySpec
+	retryMaxTimeSpec := OptionSpec{Handler: handleRetryMaxTime, NumArgs: 1}
+	optionRegistry["--retry-max-time"] = retryMaxTimeSpec
+	retryConnRefusedSpec := OptionSpec{Handler: handleRetryConnRefused, NumArgs: 0}
+	optionRegistry["--retry-connrefused"] = retryConnRefusedSpec
+	retryAllErrorsSpec := OptionSpec{Handler: handleRetryAllErrors, NumArgs: 0}
+	optionRegistry["--retry-all-errors"] = retryAllErrorsSpec
+
+	// --location-trusted (重定向时向其他主机发送认证信息)
+	locationTrustedSpec := OptionSpec{Handler: handleLocationTrusted, NumArgs: 0}
+	optionRegistry["--location-trusted"] = locationTrustedSpec
+
+	// --post301 / --post302 / --post303 (重定向时保持POST方法)
+	post301Spec := OptionSpec{Handler: handlePost301, NumArgs: 0}
+	optionRegistry["--post301"] = post301Spec
+	post302Spec := OptionSpec{Handler: handlePost302, NumArgs: 0}
+	optionRegistry["--post302"] = post302Spec
+	post303Spec := OptionSpec{Handler: handlePost303, NumArgs: 0}
+	optionRegistry["--post303"] = post303Spec
+
+	// --max-time / -m (最大执行时间)
 	maxTimeSpec := OptionSpec{Handler: handleMaxTime, NumArgs: 1}
+	optionRegistry["-m"] = maxTimeSpec
 	optionRegistry["--max-time"] = maxTimeSpec
 
 	// --proxy (HTTP代理)
@@ -142,6 +185,35 @@ func init() {
 	proxyUserSpec := OptionSpec{Handler: handleProxyUser, NumArgs: 1}
 	optionRegistry["-U"] = proxyUserSpec
 	optionRegistry["--proxy-user"] = proxyUserSpec
+	// --proxy-basic / --proxy-digest / --proxy-anyauth (代理认证方式)
+	proxyBasicSpec := OptionSpec{Handler: handleProxyBasic, NumArgs: 0}
+	optionRegistry["--proxy-basic"] = proxyBasicSpec
+	proxyDigestSpec := OptionSpec{Handler: handleProxyDigest, NumArgs: 0}
+	optionRegistry["--proxy-digest"] = proxyDigestSpec
+	proxyAnyAuthSpec := OptionSpec{Handler: handleProxyAnyAuth, NumArgs: 0}
+	optionRegistry["--proxy-anyauth"] = proxyAnyAuthSpec
+	// --noproxy (不使用代理的主机列表)
+	noProxySpec := OptionSpec{Handler: handleNoProxy, NumArgs: 1}
+	optionRegistry["--noproxy"] = noProxySpec
+	// --preproxy (连接代理之前先经过的 SOCKS 代理)
+	preProxySpec := OptionSpec{Handler: handlePreProxy, NumArgs: 1}
+	optionRegistry["--preproxy"] = preProxySpec
+	// --proxy-header (只发送给代理的请求头)
+	proxyHeaderSpec := OptionSpec{Handler: handleProxyHeader, NumArgs: 1}
+	optionRegistry["--proxy-header"] = proxyHeaderSpec
+	// -p / --proxytunnel (通过 CONNECT 隧道访问 http:// 目标)
+	proxyTunnelSpec := OptionSpec{Handler: handleProxyTunnel, NumArgs: 0}
+	optionRegistry["-p"] = proxyTunnelSpec
+	optionRegistry["--proxytunnel"] = proxyTunnelSpec
+	// --proxy-insecure / --proxy-cacert / --proxy-capath (HTTPS 代理的证书校验)
+	proxyInsecureSpec := OptionSpec{Handler: handleProxyInsecure, NumArgs: 0}
+	optionRegistry["--proxy-insecure"] = proxyInsecureSpec
+	proxyCACertSpec := OptionSpec{Handler: handleProxyCACert, NumArgs: 1}
+	optionRegistry["--proxy-cacert"] = proxyCACertSpec
+	proxyCAPathSpec := OptionSpec{Handler: handleProxyCAPath, NumArgs: 1}
+	optionRegistry["--proxy-capath"] = proxyCAPathSpec
+	proxyPinnedPubKeySpec := OptionSpec{Handler: handleProxyPinnedPubKey, NumArgs: 1}
+	optionRegistry["--proxy-pinnedpubkey"] = proxyPinnedPubKeySpec
 
 	// --max-redirs (最大重定向次数)
 	maxRedirsSpec := OptionSpec{Handler: handleMaxRedirs, NumArgs: 1}
@@ -151,6 +223,18 @@ func init() {
 	cacertSpec := OptionSpec{Handler: handleCACert, NumArgs: 1}
 	optionRegistry["--cacert"] = cacertSpec
 
+	// --capath (CA证书目录)
+	capathSpec := OptionSpec{Handler: handleCAPath, NumArgs: 1}
+	optionRegistry["--capath"] = capathSpec
+
+	// --crlfile (证书吊销列表)
+	crlfileSpec := OptionSpec{Handler: handleCRLFile, NumArgs: 1}
+	optionRegistry["--crlfile"] = crlfileSpec
+
+	// --pinnedpubkey (固定服务器证书的公钥)
+	pinnedPubKeySpec := OptionSpec{Handler: handlePinnedPubKey, NumArgs: 1}
+	optionRegistry["--pinnedpubkey"] = pinnedPubKeySpec
+
 	// --cert (客户端证书)
 	certSpec := OptionSpec{Handler: handleClientCert, NumArgs: 1}
 	optionRegistry["--cert"] = certSpec
@@ -165,14 +249,27 @@ func init() {
 	optionRegistry["-i"] = includeSpec
 	optionRegistry["--include"] = includeSpec
 
+	// --dump-header / -D (把响应头写入文件，"-" 表示标准输出)
+	dumpHeaderSpec := OptionSpec{Handler: handleDumpHeader, NumArgs: 1}
+	optionRegistry["-D"] = dumpHeaderSpec
+	optionRegistry["--dump-header"] = dumpHeaderSpec
+
 	// --silent / -s (静默模式)
 	silentSpec := OptionSpec{Handler: handleSilent, NumArgs: 0}
 	optionRegistry["-s"] = silentSpec
 	optionRegistry["--silent"] = silentSpec
 
-	// --trace
-	traceSpec := OptionSpec{Handler: handleTrace, NumArgs: 0}
+	// --trace / --trace-ascii (记录收发的所有数据，"-" 表示标准输出)
+	traceSpec := OptionSpec{Handler: handleTrace, NumArgs: 1}
 	optionRegistry["--trace"] = traceSpec
+	traceASCIISpec := OptionSpec{Handler: handleTraceASCII, NumArgs: 1}
+	optionRegistry["--trace-ascii"] = traceASCIISpec
+
+	// --trace-time / --trace-ids (追踪输出中加入时间戳、传输和连接编号)
+	traceTimeSpec := OptionSpec{Handler: handleTraceTime, NumArgs: 0}
+	optionRegistry["--trace-time"] = traceTimeSpec
+	traceIDsSpec := OptionSpec{Handler: handleTraceIDs, NumArgs: 0}
+	optionRegistry["--trace-ids"] = traceIDsSpec
 
 	// --digest (强制Digest认证)
 	digestSpec := OptionSpec{Handler: handleDigest, NumArgs: 1}
@@ -194,6 +291,10 @@ func init() {
 	http10Spec := OptionSpec{Handler: handleHTTP10, NumArgs: 0}
 	optionRegistry["--http1.0"] = http10Spec
 
+	// --http2-prior-knowledge (不经协商直接使用HTTP/2)
+	http2PriorKnowledgeSpec := OptionSpec{Handler: handleHTTP2PriorKnowledge, NumArgs: 0}
+	optionRegistry["--http2-prior-knowledge"] = http2PriorKnowledgeSpec
+
 	// 文件输出相关选项
 	// -o/--output (指定输出文件)
 	outputSpec := OptionSpec{Handler: handleOutput, NumArgs: 1}
@@ -217,6 +318,11 @@ func init() {
 	removeOnErrorSpec := OptionSpec{Handler: handleRemoveOnError, NumArgs: 0}
 	optionRegistry["--remove-on-error"] = removeOnErrorSpec
 
+	// --append/-a (追加到输出文件)
+	appendSpec := OptionSpec{Handler: handleAppend, NumArgs: 0}
+	optionRegistry["-a"] = appendSpec
+	optionRegistry["--append"] = appendSpec
+
 	// -C/--continue-at (断点续传)
 	continueAtSpec := OptionSpec{Handler: handleContinueAt, NumArgs: 1}
 	optionRegistry["-C"] = continueAtSpec
@@ -244,6 +350,46 @@ func init() {
 	connectToSpec := OptionSpec{Handler: handleConnectTo, NumArgs: 1, CanAppearMultipleTimes: true}
 	optionRegistry["--connect-to"] = connectToSpec
 
+	// --unix-socket / --abstract-unix-socket (通过 Unix 套接字连接)
+	unixSocketSpec := OptionSpec{Handler: handleUnixSocket, NumArgs: 1}
+	optionRegistry["--unix-socket"] = unixSocketSpec
+	abstractUnixSocketSpec := OptionSpec{Handler: handleAbstractUnixSocket, NumArgs: 1}
+	optionRegistry["--abstract-unix-socket"] = abstractUnixSocketSpec
+
+	// --interface (绑定出口网络接口、IP 或主机名)
+	interfaceSpec := OptionSpec{Handler: handleInterface, NumArgs: 1}
+	optionRegistry["--interface"] = interfaceSpec
+
+	// --local-port (绑定本地端口或端口范围)
+	localPortSpec := OptionSpec{Handler: handleLocalPort, NumArgs: 1}
+	optionRegistry["--local-port"] = localPortSpec
+
+	// -4 / --ipv4, -6 / --ipv6 (只使用 IPv4 或 IPv6 地址)
+	ipv4Spec := OptionSpec{Handler: handleIPv4, NumArgs: 0}
+	optionRegistry["-4"] = ipv4Spec
+	optionRegistry["--ipv4"] = ipv4Spec
+	ipv6Spec := OptionSpec{Handler: handleIPv6, NumArgs: 0}
+	optionRegistry["-6"] = ipv6Spec
+	optionRegistry["--ipv6"] = ipv6Spec
+
+	// --tcp-nodelay (开启 TCP_NODELAY，默认已开启)
+	tcpNoDelaySpec := OptionSpec{Handler: handleTCPNoDelay, NumArgs: 0}
+	optionRegistry["--tcp-nodelay"] = tcpNoDelaySpec
+
+	// --keepalive / --no-keepalive (连接复用和TCP保活探测)
+	keepAliveSpec := OptionSpec{Handler: handleKeepAlive, NumArgs: 0}
+	optionRegistry["--keepalive"] = keepAliveSpec
+	noKeepAliveSpec := OptionSpec{Handler: handleNoKeepAlive, NumArgs: 0}
+	optionRegistry["--no-keepalive"] = noKeepAliveSpec
+
+	// --keepalive-time (TCP保活探测间隔，单位秒)
+	keepAliveTimeSpec := OptionSpec{Handler: handleKeepAliveTime, NumArgs: 1}
+	optionRegistry["--keepalive-time"] = keepAliveTimeSpec
+
+	// --tcp-fastopen (使用 TCP Fast Open)
+	tcpFastOpenSpec := OptionSpec{Handler: handleTCPFastOpen, NumArgs: 0}
+	optionRegistry["--tcp-fastopen"] = tcpFastOpenSpec
+
 	// --get / -G (GET方式发送POST数据)
 	getSpec := OptionSpec{Handler: handleGet, NumArgs: 0}
 	optionRegistry["-G"] = getSpec
@@ -479,49 +625,56 @@ func handleDataBinary(c *CURL, args ...string) error {
 
 // handleCompressed 用于处理 --compressed 选项
 func handleCompressed(c *CURL, args ...string) error {
-	// 设置标准的 Accept-Encoding 头，告诉服务器我们接受这些压缩格式
-	// 你的 requests 库支持 gzip, deflate, 和 br
-	c.Header.Set("Accept-Encoding", "gzip, deflate, br")
+	// 告诉服务器我们接受这些压缩格式，响应体由 contentDecoder 按照 Content-Encoding 解码
+	c.Compressed = true
+	c.Header.Set("Accept-Encoding", acceptEncoding)
+	return nil
+}
+
+// handleRaw 用于处理 --raw 选项 (不按照 Content-Encoding 解码响应体)
+func handleRaw(c *CURL, args ...string) error {
+	c.Raw = true
 	return nil
 }
 
-// handleSocks5 用于处理 --socks5 选项
+// handleSocks5 用于处理 --socks5 选项 (在本地解析主机名)
 func handleSocks5(c *CURL, args ...string) error {
-	proxyAddr := args[0]
-	// 你的 SetProxy 函数需要一个完整的 URL（例如 socks5://localhost:1080）
-	// 为用户着想，如果他们只提供了 host:port，我们自动添加 scheme
-	if !strings.HasPrefix(proxyAddr, "socks5://") {
-		proxyAddr = "socks5://" + proxyAddr
+	return setSocksProxy(c, "socks5", args[0])
+}
+
+// handleSocks5Hostname 用于处理 --socks5-hostname 选项 (由代理解析主机名)
+func handleSocks5Hostname(c *CURL, args ...string) error {
+	return setSocksProxy(c, "socks5h", args[0])
+}
+
+// handleSocks4 用于处理 --socks4 选项 (在本地解析主机名)
+func handleSocks4(c *CURL, args ...string) error {
+	return setSocksProxy(c, "socks4", args[0])
+}
+
+// handleSocks4a 用于处理 --socks4a 选项 (由代理解析主机名)
+func handleSocks4a(c *CURL, args ...string) error {
+	return setSocksProxy(c, "socks4a", args[0])
+}
+
+// setSocksProxy 设置 SOCKS 代理，与 curl 一致会替换 -x 指定的代理
+// 只提供了 host:port 时自动添加 scheme
+func setSocksProxy(c *CURL, scheme, proxyAddr string) error {
+	if !strings.Contains(proxyAddr, "://") {
+		proxyAddr = scheme + "://" + proxyAddr
 	}
 	c.Proxy = proxyAddr
+	c.proxySet = true
 	return nil
 }
 
 // handleConnectTimeout 用于处理 --connect-timeout 选项
 func handleConnectTimeout(c *CURL, args ...string) error {
-	connectTimeoutStr := args[0]
-
-	// 支持单位：s(秒), m(分), h(小时)，如 "30s", "5m", "1h"
-	if strings.HasSuffix(connectTimeoutStr, "s") || strings.HasSuffix(connectTimeoutStr, "m") || strings.HasSuffix(connectTimeoutStr, "h") {
-		duration, err := time.ParseDuration(connectTimeoutStr)
-		if err != nil {
-			return fmt.Errorf("invalid value for --connect-timeout: %w", err)
-		}
-		if duration < 0 {
-			return fmt.Errorf("connect-timeout must be non-negative: %s", connectTimeoutStr)
-		}
-		c.ConnectTimeout = duration
-	} else {
-		// 纯数字，按秒处理
-		timeout, err := strconv.Atoi(connectTimeoutStr)
-		if err != nil {
-			return fmt.Errorf("invalid value for --connect-timeout: %w", err)
-		}
-		if timeout < 0 {
-			return fmt.Errorf("connect-timeout must be non-negative: %d", timeout)
-		}
-		c.ConnectTimeout = time.Duration(timeout) * time.Second
+	timeout, err := parseTimeoutValue(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid value for --connect-timeout: %w", err)
 	}
+	c.ConnectTimeout = timeout
 	return nil
 } // handleDataUrlencode 用于处理 --data-urlencode 选项
 // 支持以下语法格式：
@@ -685,13 +838,27 @@ func handleLimitRate(c *CURL, args ...string) error {
 	// --limit-rate 选项用于限制传输速度
 	// 参数可以是 bytes/second 或带单位的值 (如 200K, 1M)
 	limitRate := args[0]
+	if _, err := parseSizeValue(limitRate); err != nil {
+		return fmt.Errorf("invalid value for --limit-rate: %w", err)
+	}
 
-	// 存储限速设置，具体的限速逻辑由底层的 requests 库处理
+	// 存储限速设置，执行时由 configureRateLimit 对上传和下载分别限速
 	c.LimitRate = limitRate
 
 	return nil
 }
 
+// handleMaxFileSize 用于处理 --max-filesize 选项
+// 参数为字节数，支持与 --limit-rate 相同的 K/M/G 后缀，0 表示不限制
+func handleMaxFileSize(c *CURL, args ...string) error {
+	size, err := parseSizeValue(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid value for --max-filesize: %w", err)
+	}
+	c.MaxFileSize = size
+	return nil
+}
+
 // handleCookie 用于处理 -b / --cookie 选项
 func handleCookie(c *CURL, args ...string) error {
 	cookieValue := args[0]
@@ -771,47 +938,218 @@ func handleForm(c *CURL, args ...string) error {
 // handleLocation 用于处理 --location / -L 选项 (重定向跟随)
 func handleLocation(c *CURL, args ...string) error {
 	c.FollowRedirect = true
-	// 如果MaxRedirs还是默认值，设置为一个合理的默认重定向次数
-	if c.MaxRedirs == -1 {
-		c.MaxRedirs = 30 // curl的默认值
+	// 如果没有指定 --max-redirs，设置为一个合理的默认重定向次数
+	if !c.maxRedirsSet {
+		c.MaxRedirs = defaultMaxRedirs // curl的默认值
+	}
+	return nil
+}
+
+// handleRetry 用于处理 --retry 选项 (遇到临时错误时的重试次数)
+func handleRetry(c *CURL, args ...string) error {
+	retry, err := strconv.Atoi(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid retry value: %s", args[0])
 	}
+	if retry < 0 {
+		return fmt.Errorf("retry must be non-negative: %d", retry)
+	}
+	c.Retry = retry
+	return nil
+}
+
+// handleRetryDelay 用于处理 --retry-delay 选项 (重试间隔，设置后不再使用指数退避)
+func handleRetryDelay(c *CURL, args ...string) error {
+	delay, err := parseTimeoutValue(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid value for --retry-delay: %w", err)
+	}
+	c.RetryDelay = delay
+	return nil
+}
+
+// handleRetryMaxTime 用于处理 --retry-max-time 选项 (允许重试的最长时间)
+func handleRetryMaxTime(c *CURL, args ...string) error {
+	maxTime, err := parseTimeoutValue(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid value for --retry-max-time: %w", err)
+	}
+	c.RetryMaxTime = maxTime
+	return nil
+}
+
+// handleRetryConnRefused 用于处理 --retry-connrefused 选项 (连接被拒绝时也重试)
+func handleRetryConnRefused(c *CURL, args ...string) error {
+	c.RetryConnRefused = true
+	return nil
+}
+
+// handleRetryAllErrors 用于处理 --retry-all-errors 选项 (任何错误都重试)
+func handleRetryAllErrors(c *CURL, args ...string) error {
+	c.RetryAllErrors = true
+	return nil
+}
+
+// handleLocationTrusted 用于处理 --location-trusted 选项
+// 与 -L 相同，但重定向到其他主机时仍然发送认证信息和 Cookie 头
+func handleLocationTrusted(c *CURL, args ...string) error {
+	c.LocationTrusted = true
+	return handleLocation(c)
+}
+
+// handlePost301 用于处理 --post301 选项 (301重定向时不把POST改为GET)
+func handlePost301(c *CURL, args ...string) error {
+	c.Post301 = true
+	return nil
+}
+
+// handlePost302 用于处理 --post302 选项 (302重定向时不把POST改为GET)
+func handlePost302(c *CURL, args ...string) error {
+	c.Post302 = true
+	return nil
+}
+
+// handlePost303 用于处理 --post303 选项 (303重定向时不把POST改为GET)
+func handlePost303(c *CURL, args ...string) error {
+	c.Post303 = true
 	return nil
 }
 
 // handleMaxTime 用于处理 --max-time 选项 (最大执行时间)
 func handleMaxTime(c *CURL, args ...string) error {
-	maxTime := args[0]
+	timeout, err := parseTimeoutValue(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid max-time value: %w", err)
+	}
+	c.Timeout = timeout
+	return nil
+}
 
-	// 解析时间值（可能是秒数或带单位的值）
-	// 支持单位：s(秒), m(分), h(小时)，如 "30s", "5m", "1h"
-	if strings.HasSuffix(maxTime, "s") || strings.HasSuffix(maxTime, "m") || strings.HasSuffix(maxTime, "h") {
-		duration, err := time.ParseDuration(maxTime)
+// parseTimeoutValue 解析超时时间
+// 与 curl 一致，纯数字按秒处理并支持小数（如 "0.5"）；也支持带单位的值，如 "30s", "5m", "1h"
+func parseTimeoutValue(value string) (time.Duration, error) {
+	if strings.HasSuffix(value, "s") || strings.HasSuffix(value, "m") || strings.HasSuffix(value, "h") {
+		duration, err := time.ParseDuration(value)
 		if err != nil {
-			return fmt.Errorf("invalid max-time value: %s", maxTime)
+			return 0, err
 		}
 		if duration < 0 {
-			return fmt.Errorf("max-time must be non-negative: %s", maxTime)
+			return 0, fmt.Errorf("timeout must be non-negative: %s", value)
 		}
-		c.Timeout = duration
-	} else {
-		// 纯数字，按秒处理
-		timeout, err := strconv.Atoi(maxTime)
-		if err != nil {
-			return fmt.Errorf("invalid max-time value: %s", maxTime)
+		return duration, nil
+	}
+
+	seconds, err := strconv.ParseFloat(value, 64)
+	if err != nil || math.IsNaN(seconds) || math.IsInf(seconds, 0) {
+		return 0, fmt.Errorf("not a number: %s", value)
+	}
+	if seconds < 0 {
+		return 0, fmt.Errorf("timeout must be non-negative: %s", value)
+	}
+	return time.Duration(seconds * float64(time.Second)), nil
+}
+
+// parseSizeValue 解析字节数
+// 与 curl 一致，支持 K/M/G/T 后缀（不区分大小写，按 1024 进位），数字部分可以是小数，如 "200K", "1.5M"
+func parseSizeValue(value string) (int64, error) {
+	number := value
+	multiplier := 1.0
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'k', 'K':
+			multiplier = 1 << 10
+		case 'm', 'M':
+			multiplier = 1 << 20
+		case 'g', 'G':
+			multiplier = 1 << 30
+		case 't', 'T':
+			multiplier = 1 << 40
 		}
-		if timeout < 0 {
-			return fmt.Errorf("max-time must be non-negative: %d", timeout)
+		if multiplier != 1 {
+			number = value[:n-1]
 		}
-		c.Timeout = time.Duration(timeout) * time.Second
 	}
 
-	return nil
+	size, err := strconv.ParseFloat(number, 64)
+	if err != nil || math.IsNaN(size) || math.IsInf(size, 0) {
+		return 0, fmt.Errorf("not a size: %s", value)
+	}
+	if size < 0 {
+		return 0, fmt.Errorf("size must be non-negative: %s", value)
+	}
+	total := size * multiplier
+	if total >= math.MaxInt64 {
+		return 0, fmt.Errorf("size too large: %s", value)
+	}
+	return int64(total), nil
 }
 
-// handleProxy 用于处理 --proxy / -x 选项 (HTTP代理)
+// handleProxy 用于处理 --proxy / -x 选项 (HTTP/HTTPS/SOCKS代理)
+// 没有 scheme 时使用 http://，-x "" 表示不使用代理，也不读取代理环境变量
 func handleProxy(c *CURL, args ...string) error {
 	proxy := args[0]
 	c.Proxy = proxy
+	c.proxySet = true
+	return nil
+}
+
+// handleNoProxy 用于处理 --noproxy 选项 (不使用代理的主机列表，覆盖 no_proxy 环境变量)
+func handleNoProxy(c *CURL, args ...string) error {
+	c.NoProxy = args[0]
+	c.noProxySet = true
+	return nil
+}
+
+// handlePreProxy 用于处理 --preproxy 选项 (连接代理之前先经过的 SOCKS 代理)
+func handlePreProxy(c *CURL, args ...string) error {
+	c.PreProxy = args[0]
+	return nil
+}
+
+// handleProxyHeader 用于处理 --proxy-header 选项 (只发送给代理的请求头)
+// 与 curl 一致，"Name:" 去掉该头部
+func handleProxyHeader(c *CURL, args ...string) error {
+	if strings.TrimSpace(args[0]) == "" {
+		return nil
+	}
+	key, value, err := parseHTTPHeaderKeyValue(args[0])
+	if err != nil {
+		return fmt.Errorf("invalid proxy header format: %w", err)
+	}
+	if c.ProxyHeader == nil {
+		c.ProxyHeader = make(http.Header)
+	}
+	c.ProxyHeader.Add(key, value)
+	return nil
+}
+
+// handleProxyTunnel 用于处理 -p, --proxytunnel 选项 (通过 CONNECT 隧道访问 http:// 目标)
+func handleProxyTunnel(c *CURL, args ...string) error {
+	c.ProxyTunnel = true
+	return nil
+}
+
+// handleProxyInsecure 用于处理 --proxy-insecure 选项 (不校验 HTTPS 代理的证书)
+func handleProxyInsecure(c *CURL, args ...string) error {
+	c.ProxyInsecure = true
+	return nil
+}
+
+// handleProxyCACert 用于处理 --proxy-cacert 选项 (校验 HTTPS 代理的CA证书)
+func handleProxyCACert(c *CURL, args ...string) error {
+	c.ProxyCACert = args[0]
+	return nil
+}
+
+// handleProxyCAPath 用于处理 --proxy-capath 选项 (校验 HTTPS 代理的CA证书目录)
+func handleProxyCAPath(c *CURL, args ...string) error {
+	c.ProxyCAPath = args[0]
+	return nil
+}
+
+// handleProxyPinnedPubKey 用于处理 --proxy-pinnedpubkey 选项 (HTTPS 代理证书固定的公钥)
+func handleProxyPinnedPubKey(c *CURL, args ...string) error {
+	c.ProxyPinnedPubKey = args[0]
 	return nil
 }
 
@@ -827,6 +1165,24 @@ func handleProxyUser(c *CURL, args ...string) error {
 	return nil
 }
 
+// handleProxyBasic 用于处理 --proxy-basic 选项 (预先发送 Basic 代理认证，默认行为)
+func handleProxyBasic(c *CURL, args ...string) error {
+	c.ProxyAuth = "basic"
+	return nil
+}
+
+// handleProxyDigest 用于处理 --proxy-digest 选项 (应答代理的 Digest 认证挑战)
+func handleProxyDigest(c *CURL, args ...string) error {
+	c.ProxyAuth = "digest"
+	return nil
+}
+
+// handleProxyAnyAuth 用于处理 --proxy-anyauth 选项 (根据代理的挑战自动选择认证方式)
+func handleProxyAnyAuth(c *CURL, args ...string) error {
+	c.ProxyAuth = "any"
+	return nil
+}
+
 // handleMaxRedirs 用于处理 --max-redirs 选项 (最大重定向次数)
 func handleMaxRedirs(c *CURL, args ...string) error {
 	maxRedirs := args[0]
@@ -836,11 +1192,13 @@ func handleMaxRedirs(c *CURL, args ...string) error {
 		return fmt.Errorf("invalid max-redirs value: %s", maxRedirs)
 	}
 
-	if redirs < 0 {
-		return fmt.Errorf("max-redirs must be non-negative: %d", redirs)
+	// 与 curl 一致，-1 表示不限制重定向次数
+	if redirs < -1 {
+		return fmt.Errorf("max-redirs must be -1 (unlimited) or non-negative: %d", redirs)
 	}
 
 	c.MaxRedirs = redirs
+	c.maxRedirsSet = true
 	return nil
 }
 
@@ -857,6 +1215,42 @@ func handleCACert(c *CURL, args ...string) error {
 	return nil
 }
 
+// handleCAPath 用于处理 --capath 选项 (CA证书目录)
+func handleCAPath(c *CURL, args ...string) error {
+	dirPath := args[0]
+
+	info, err := os.Stat(dirPath)
+	if os.IsNotExist(err) {
+		return fmt.Errorf("CA certificate directory not found: %s", dirPath)
+	}
+	if err == nil && !info.IsDir() {
+		return fmt.Errorf("CA certificate path is not a directory: %s", dirPath)
+	}
+
+	c.CAPath = dirPath
+	return nil
+}
+
+// handleCRLFile 用于处理 --crlfile 选项 (证书吊销列表)
+func handleCRLFile(c *CURL, args ...string) error {
+	crlPath := args[0]
+
+	// 检查CRL文件是否存在
+	if _, err := os.Stat(crlPath); os.IsNotExist(err) {
+		return fmt.Errorf("CRL file not found: %s", crlPath)
+	}
+
+	c.CRLFile = crlPath
+	return nil
+}
+
+// handlePinnedPubKey 用于处理 --pinnedpubkey 选项 (公钥文件或 sha256//base64 哈希)
+// 参数在创建 Session 时解析，格式错误由之后的请求返回
+func handlePinnedPubKey(c *CURL, args ...string) error {
+	c.PinnedPubKey = args[0]
+	return nil
+}
+
 // handleClientCert 用于处理 --cert 选项 (客户端证书)
 func handleClientCert(c *CURL, args ...string) error {
 	certPath := args[0]
@@ -884,8 +1278,24 @@ func handleClientKey(c *CURL, args ...string) error {
 }
 
 // handleWriteOut 用于处理 -w/--write-out 选项 (格式化输出)
+// 与 curl 一致，"@file" 从文件读取格式，"@-" 从标准输入读取
 func handleWriteOut(c *CURL, args ...string) error {
-	c.WriteOutFormat = args[0]
+	format := args[0]
+	c.writeOutStdin = false
+	if path, ok := strings.CutPrefix(format, "@"); ok {
+		// 解析时不读取标准输入，在 -w 第一次输出时从 c.Stdin 读取
+		if path == "-" {
+			c.WriteOutFormat = ""
+			c.writeOutStdin = true
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("failed to read write-out format from %s: %w", path, err)
+		}
+		format = string(data)
+	}
+	c.WriteOutFormat = format
 	return nil
 }
 
@@ -903,22 +1313,25 @@ func handleFail(c *CURL, args ...string) error {
 
 // handleHTTP2 用于处理 --http2 选项 (强制HTTP/2)
 func handleHTTP2(c *CURL, args ...string) error {
-	c.HTTP2 = true
-	c.HTTPVersion = HTTPVersion2
+	c.setHTTPVersionPreference("2")
+	return nil
+}
+
+// handleHTTP2PriorKnowledge 用于处理 --http2-prior-knowledge 选项 (明文连接直接使用HTTP/2)
+func handleHTTP2PriorKnowledge(c *CURL, args ...string) error {
+	c.setHTTPVersionPreference("2-prior-knowledge")
 	return nil
 }
 
 // handleHTTP11 用于处理 --http1.1 选项 (强制HTTP/1.1)
 func handleHTTP11(c *CURL, args ...string) error {
-	c.HTTP2 = false
-	c.HTTPVersion = HTTPVersion11
+	c.setHTTPVersionPreference("1.1")
 	return nil
 }
 
 // handleHTTP10 用于处理 --http1.0 选项 (强制HTTP/1.0)
 func handleHTTP10(c *CURL, args ...string) error {
-	c.HTTP2 = false
-	c.HTTPVersion = HTTPVersion10
+	c.setHTTPVersionPreference("1.0")
 	return nil
 }
 
@@ -945,6 +1358,12 @@ func handleVerbose(c *CURL, args ...string) error {
 	return nil
 }
 
+// handleDumpHeader 用于处理 -D, --dump-header 选项 (转储响应头)
+func handleDumpHeader(c *CURL, args ...string) error {
+	c.DumpHeader = args[0]
+	return nil
+}
+
 // handleInclude 用于处理 -i, --include 选项 (包含响应头)
 func handleInclude(c *CURL, args ...string) error {
 	c.Include = true
@@ -957,9 +1376,35 @@ func handleSilent(c *CURL, args ...string) error {
 	return nil
 }
 
-// handleTrace 用于处理 --trace 选项 (追踪模式)
+// handleTrace 用于处理 --trace 选项 (以十六进制和文本记录收发的数据)
+// 参数是输出文件，"-" 表示标准输出
 func handleTrace(c *CURL, args ...string) error {
 	c.Trace = true
+	c.TraceASCII = false
+	if len(args) > 0 {
+		c.TraceFile = args[0]
+	}
+	return nil
+}
+
+// handleTraceASCII 用于处理 --trace-ascii 选项 (只以文本记录收发的数据)
+func handleTraceASCII(c *CURL, args ...string) error {
+	if err := handleTrace(c, args...); err != nil {
+		return err
+	}
+	c.TraceASCII = true
+	return nil
+}
+
+// handleTraceTime 用于处理 --trace-time 选项
+func handleTraceTime(c *CURL, args ...string) error {
+	c.TraceTime = true
+	return nil
+}
+
+// handleTraceIDs 用于处理 --trace-ids 选项
+func handleTraceIDs(c *CURL, args ...string) error {
+	c.TraceIDs = true
 	return nil
 }
 
@@ -999,6 +1444,12 @@ func handleRemoveOnError(c *CURL, args ...string) error {
 	return nil
 }
 
+// handleAppend 用于处理 -a, --append 选项 (追加到输出文件而不是覆盖)
+func handleAppend(c *CURL, args ...string) error {
+	c.Append = true
+	return nil
+}
+
 // handleContinueAt 用于处理 -C, --continue-at 选项 (断点续传)
 func handleContinueAt(c *CURL, args ...string) error {
 	continueAt := args[0]
@@ -1056,25 +1507,114 @@ func handleResolve(c *CURL, args ...string) error {
 	return nil
 }
 
+// handleUnixSocket 用于处理 --unix-socket 选项
+func handleUnixSocket(c *CURL, args ...string) error {
+	if args[0] == "" {
+		return fmt.Errorf("--unix-socket requires a path")
+	}
+	c.UnixSocket = args[0]
+	c.AbstractUnixSocket = false
+	return nil
+}
+
+// handleAbstractUnixSocket 用于处理 --abstract-unix-socket 选项 (Linux 抽象命名空间)
+func handleAbstractUnixSocket(c *CURL, args ...string) error {
+	if args[0] == "" {
+		return fmt.Errorf("--abstract-unix-socket requires a name")
+	}
+	c.UnixSocket = args[0]
+	c.AbstractUnixSocket = true
+	return nil
+}
+
+// handleInterface 用于处理 --interface 选项
+// 支持接口名、IP、主机名，以及 "if!" 和 "host!" 前缀
+func handleInterface(c *CURL, args ...string) error {
+	value := strings.TrimSpace(args[0])
+	if _, name, ok := strings.Cut(value, "!"); value == "" || (ok && name == "") {
+		return fmt.Errorf("invalid value for --interface: %q", args[0])
+	}
+	c.Interface = value
+	return nil
+}
+
+// handleLocalPort 用于处理 --local-port 选项，支持 N 或 N-M
+func handleLocalPort(c *CURL, args ...string) error {
+	if _, _, err := parseLocalPort(args[0]); err != nil {
+		return fmt.Errorf("invalid value for --local-port: %w", err)
+	}
+	c.LocalPort = args[0]
+	return nil
+}
+
+// handleIPv4 用于处理 -4, --ipv4 选项
+func handleIPv4(c *CURL, args ...string) error {
+	c.IPVersion = 4
+	return nil
+}
+
+// handleIPv6 用于处理 -6, --ipv6 选项
+func handleIPv6(c *CURL, args ...string) error {
+	c.IPVersion = 6
+	return nil
+}
+
+// handleTCPNoDelay 用于处理 --tcp-nodelay 选项
+func handleTCPNoDelay(c *CURL, args ...string) error {
+	c.TCPNoDelay = true
+	return nil
+}
+
+// handleKeepAlive 用于处理 --keepalive 选项 (复用连接并开启TCP保活探测)
+func handleKeepAlive(c *CURL, args ...string) error {
+	c.KeepAlive = true
+	c.TCPKeepAlive = true
+	return nil
+}
+
+// handleNoKeepAlive 用于处理 --no-keepalive 选项 (不复用连接并关闭TCP保活探测)
+func handleNoKeepAlive(c *CURL, args ...string) error {
+	c.KeepAlive = false
+	c.TCPKeepAlive = false
+	return nil
+}
+
+// handleKeepAliveTime 用于处理 --keepalive-time 选项，参数为正整数秒
+func handleKeepAliveTime(c *CURL, args ...string) error {
+	seconds, err := strconv.Atoi(args[0])
+	if err != nil || seconds <= 0 {
+		return fmt.Errorf("invalid value for --keepalive-time: %s", args[0])
+	}
+	c.KeepAliveTime = time.Duration(seconds) * time.Second
+	c.TCPKeepAlive = true
+	return nil
+}
+
+// handleTCPFastOpen 用于处理 --tcp-fastopen 选项
+func handleTCPFastOpen(c *CURL, args ...string) error {
+	c.TCPFastOpen = true
+	return nil
+}
+
 // handleConnectTo 用于处理 --connect-to 选项 (连接重定向映射)
 // 格式：--connect-to HOST1:PORT1:HOST2:PORT2
 // 例如：--connect-to example.com:443:127.0.0.1:8443
 //
 //	--connect-to api.example.com:80:localhost:8080
 //	--connect-to ::proxy.example.com:8080  (任意主机端口到代理)
+//
+// HOST1 或 PORT1 为空时匹配任意主机或端口，PORT2 为空时保持原端口。
+// 多条映射按出现顺序匹配，只使用第一条匹配的映射。
 func handleConnectTo(c *CURL, args ...string) error {
 	connectMapping := args[0]
 
 	// 基本格式验证：HOST1:PORT1:HOST2:PORT2
-	parts := strings.Split(connectMapping, ":")
-	if len(parts) != 4 {
-		return fmt.Errorf("invalid --connect-to format, expected HOST1:PORT1:HOST2:PORT2, got: %s", connectMapping)
+	entry, err := parseConnectToEntry(connectMapping)
+	if err != nil {
+		return err
 	}
 
-	sourceHost, sourcePort, targetHost, targetPort := parts[0], parts[1], parts[2], parts[3]
-
-	// 避免 lint 警告，这些变量在实际实现中会被使用
-	_ = sourceHost
+	sourcePort, targetHost, targetPort := entry.SourcePort, entry.TargetHost, entry.TargetPort
 
 	// 验证端口是有效数字（除非为空，表示任意端口）
 	if sourcePort != "" {
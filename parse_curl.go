@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/base64"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
@@ -20,10 +21,11 @@ import (
 type HTTPVersion int
 
 const (
-	HTTPVersionAuto HTTPVersion = iota // 自动选择 (默认)
-	HTTPVersion10                      // HTTP/1.0
-	HTTPVersion11                      // HTTP/1.1
-	HTTPVersion2                       // HTTP/2
+	HTTPVersionAuto            HTTPVersion = iota // 自动选择 (默认)
+	HTTPVersion10                                 // HTTP/1.0
+	HTTPVersion11                                 // HTTP/1.1
+	HTTPVersion2                                  // HTTP/2
+	HTTPVersion2PriorKnowledge                    // HTTP/2 prior knowledge (明文 h2c)
 )
 
 // String 返回协议版本的字符串表示
@@ -35,6 +37,8 @@ func (hv HTTPVersion) String() string {
 		return "HTTP/1.1"
 	case HTTPVersion2:
 		return "HTTP/2"
+	case HTTPVersion2PriorKnowledge:
+		return "HTTP/2 (prior knowledge)"
 	case HTTPVersionAuto:
 		return "Auto"
 	default:
@@ -185,9 +189,21 @@ type CURL struct {
 	ContentType   string    // Content-Type 头
 
 	// 代理相关
-	Proxy         string // 代理服务器地址
-	ProxyUser     string // 代理用户名
-	ProxyPassword string // 代理密码
+	Proxy             string      // -x/--proxy/--socks* 代理地址，没有 scheme 时为 http://，为空时读取 http_proxy 等环境变量
+	ProxyUser         string      // 代理用户名
+	ProxyPassword     string      // 代理密码
+	ProxyAuth         string      // --proxy-basic/--proxy-digest/--proxy-anyauth 代理认证方式："basic"（默认，预先发送）、"digest"、"any"
+	PreProxy          string      // --preproxy 连接代理之前先经过的 SOCKS 代理
+	NoProxy           string      // --noproxy 不使用代理的主机列表，逗号分隔，"*" 表示所有主机
+	ProxyHeader       http.Header // --proxy-header 只发送给代理的请求头，值为空表示去掉该头部
+	ProxyTunnel       bool        // -p/--proxytunnel http:// 目标也通过 CONNECT 隧道访问
+	ProxyInsecure     bool        // --proxy-insecure 不校验 HTTPS 代理的证书
+	ProxyCACert       string      // --proxy-cacert 校验 HTTPS 代理使用的CA证书
+	ProxyCAPath       string      // --proxy-capath 校验 HTTPS 代理使用的CA证书目录
+	ProxyPinnedPubKey string      // --proxy-pinnedpubkey HTTPS 代理证书固定的公钥，格式与 PinnedPubKey 相同
+
+	proxySet   bool // 命令行中出现过 -x，-x "" 时不读取代理环境变量
+	noProxySet bool // 命令行中出现过 --noproxy，不再读取 no_proxy 环境变量
 
 	// SSL/TLS相关
 	Insecure      bool   // -k/--insecure 忽略SSL证书错误
@@ -198,6 +214,7 @@ type CURL struct {
 	KeyType       string // --key-type 私钥类型
 	CAPath        string // --capath CA证书目录
 	CRLFile       string // --crlfile 证书吊销列表
+	PinnedPubKey  string // --pinnedpubkey 服务器证书固定的公钥：PEM/DER 公钥文件，或 sha256//base64 哈希（多个用 ; 分隔）
 	SSLVerifyPeer bool   // SSL对等验证
 	SSLVerifyHost bool   // SSL主机验证
 	TLSVersion    string // TLS版本
@@ -208,25 +225,32 @@ type CURL struct {
 	ConnectTimeout      time.Duration // 连接超时
 	DNSTimeout          time.Duration // DNS解析超时
 	TLSHandshakeTimeout time.Duration // TLS握手超时
-	MaxRedirs           int           // --max-redirs 最大重定向次数
+	MaxRedirs           int           // --max-redirs 最大重定向次数，-1 表示不限制
 	Retry               int           // --retry 重试次数
 	RetryDelay          time.Duration // --retry-delay 重试延迟
 	RetryMaxTime        time.Duration // --retry-max-time 最大重试时间
 	RetryAllErrors      bool          // --retry-all-errors 重试所有错误
 	RetryConnRefused    bool          // --retry-connrefused 连接被拒绝时重试
 
+	maxRedirsSet bool // 命令行中出现过 --max-redirs，-1 表示不限制而不是未指定
+
 	// HTTP协议相关
-	HTTP2          bool        // --http2 强制使用HTTP/2
-	HTTPVersion    HTTPVersion // 协议版本控制
-	FollowRedirect bool        // -L/--location 是否跟随重定向
-	MaxFileSize    int64       // --max-filesize 最大文件大小
-	LimitRate      string      // --limit-rate 传输速度限制
-	KeepAlive      bool        // --keepalive 保持连接
-	TCPNoDelay     bool        // --tcp-nodelay TCP无延迟
-	TCPKeepAlive   bool        // --tcp-keepalive TCP保活
-	Interface      string      // --interface 网络接口
-	LocalPort      string      // --local-port 本地端口
-	IPVersion      int         // 4 或 6，IP版本
+	HTTP2          bool          // --http2 强制使用HTTP/2
+	HTTPVersion    HTTPVersion   // 协议版本控制
+	FollowRedirect bool          // -L/--location 是否跟随重定向
+	MaxFileSize    int64         // --max-filesize 最大文件大小
+	LimitRate      string        // --limit-rate 传输速度限制
+	KeepAlive      bool          // 在同一个 Session 的请求之间复用连接（默认开启），--no-keepalive 关闭
+	TCPNoDelay     bool          // --tcp-nodelay TCP无延迟（默认开启）
+	TCPKeepAlive   bool          // TCP保活探测（默认开启），--no-keepalive 关闭
+	KeepAliveTime  time.Duration // --keepalive-time TCP保活探测的间隔，0 表示使用系统默认
+	TCPFastOpen    bool          // --tcp-fastopen 使用 TCP Fast Open
+	Interface      string        // --interface 网络接口
+	LocalPort      string        // --local-port 本地端口
+	IPVersion      int           // 4 或 6，IP版本
+
+	UnixSocket         string // --unix-socket/--abstract-unix-socket 连接的 Unix 套接字路径
+	AbstractUnixSocket bool   // --abstract-unix-socket 使用 Linux 抽象命名空间
 
 	// DNS 和网络解析相关
 	Resolve   []string // --resolve 主机名解析映射，格式：host:port:address
@@ -242,11 +266,21 @@ type CURL struct {
 	Silent     bool   // -s/--silent 静默模式
 	ShowError  bool   // -S/--show-error 显示错误
 	FailEarly  bool   // --fail-early 早期失败
-	Trace      bool   // --trace 追踪所有传入和传出的数据
-	TraceFile  string // --trace-ascii 追踪文件
+	Trace      bool   // --trace/--trace-ascii 追踪所有传入和传出的数据
+	TraceFile  string // --trace/--trace-ascii 追踪文件，"-" 或空表示标准输出
+	TraceASCII bool   // --trace-ascii 只输出文本，不输出十六进制
+	TraceTime  bool   // --trace-time 追踪和 -v 输出中加入时间戳
+	TraceIDs   bool   // --trace-ids 追踪输出中加入传输和连接编号
 	DumpHeader string // -D/--dump-header 转储头文件
 	WriteOut   string // -w/--write-out 输出格式
 
+	// Stdout 是 "-D -"、-w 等写到标准输出的内容的目标，为 nil 时使用 os.Stdout
+	Stdout io.Writer
+	// Stderr 是 -w 中 %{stderr} 之后内容的目标，为 nil 时使用 os.Stderr
+	Stderr io.Writer
+	// Stdin 是 "-w @-" 读取格式的来源，为 nil 时使用 os.Stdin
+	Stdin io.Reader
+
 	// 文件输出控制
 	OutputFile       string // -o/--output 指定输出文件路径
 	RemoteName       bool   // -O/--remote-name 使用远程文件名
@@ -259,8 +293,12 @@ type CURL struct {
 
 	// 脚本与易用性增强功能
 	WriteOutFormat  string // -w/--write-out 指定输出格式
+	writeOutStdin   bool   // -w @-，第一次输出时才从 Stdin 读取格式
 	FailOnError     bool   // -f/--fail 脚本错误处理，遇到4xx/5xx返回错误
 	LocationTrusted bool   // --location-trusted 信任重定向位置
+	Post301         bool   // --post301 301重定向时保持POST
+	Post302         bool   // --post302 302重定向时保持POST
+	Post303         bool   // --post303 303重定向时保持POST
 
 	// 表单和数据相关
 	FormData   map[string]string // 表单数据
@@ -307,18 +345,50 @@ func (c *CURL) setRawBodyString(data string) {
 }
 
 // SaveToFile 将响应内容保存到文件
+// 响应体已经完整读入内存，大文件请使用 Download 流式写入
 func (c *CURL) SaveToFile(response *requests.Response) error {
 	if response == nil {
 		return fmt.Errorf("response is nil")
 	}
 
-	// 确定输出文件路径
+	outputPath, err := c.outputPathFor(response.GetHeader())
+	if err != nil {
+		return err
+	}
+	if outputPath == "" {
+		return nil
+	}
+
+	// 追加、断点续传时根据响应状态决定追加还是重写
+	flags, write, err := c.outputOpenFlags(response.GetStatusCode(), response.GetHeader())
+	if err != nil || !write {
+		return err
+	}
+
+	// 打开文件
+	file, err := os.OpenFile(outputPath, flags, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer file.Close()
+
+	// 写入内容
+	content := response.Content()
+	if _, err := file.Write(content); err != nil {
+		return fmt.Errorf("failed to write to file: %w", err)
+	}
+
+	return nil
+}
+
+// outputPathFor 根据 -J/-o/-O/--output-dir 确定输出文件路径，需要时创建目录
+// 未指定任何输出选项时返回空字符串
+func (c *CURL) outputPathFor(header http.Header) (string, error) {
 	var outputPath string
 
 	// 1. Content-Disposition 文件名 (-J)
 	if c.RemoteHeaderName {
-		hdr := response.GetHeader()
-		if cd := hdr.Get("Content-Disposition"); cd != "" {
+		if cd := header.Get("Content-Disposition"); cd != "" {
 			re := regexp.MustCompile(`filename\*?=([^;]+)`)
 			if m := re.FindStringSubmatch(cd); len(m) == 2 {
 				filename := strings.Trim(m[1], `"`)
@@ -338,10 +408,10 @@ func (c *CURL) SaveToFile(response *requests.Response) error {
 		var err error
 		outputPath, err = c.determineOutputPath()
 		if err != nil {
-			return fmt.Errorf("failed to determine output path: %w", err)
+			return "", fmt.Errorf("failed to determine output path: %w", err)
 		}
 		if outputPath == "" {
-			return nil
+			return "", nil
 		}
 	}
 
@@ -349,24 +419,28 @@ func (c *CURL) SaveToFile(response *requests.Response) error {
 	if c.CreateDirs {
 		dir := filepath.Dir(outputPath)
 		if err := os.MkdirAll(dir, 0755); err != nil {
-			return fmt.Errorf("failed to create directories: %w", err)
+			return "", fmt.Errorf("failed to create directories: %w", err)
 		}
 	}
+	return outputPath, nil
+}
 
-	// 打开文件
-	file, err := os.Create(outputPath)
-	if err != nil {
-		return fmt.Errorf("failed to create file: %w", err)
+// outputOpenFlags 返回打开输出文件的方式，write 为 false 时表示无需写入
+func (c *CURL) outputOpenFlags(status int, header http.Header) (flags int, write bool, err error) {
+	flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if c.ContinueAt != 0 {
+		offset, err := c.resumeOffset()
+		if err != nil {
+			return 0, false, err
+		}
+		if flags, write, err = c.resumeOpenFlags(status, header, offset); err != nil || !write {
+			return flags, write, err
+		}
 	}
-	defer file.Close()
-
-	// 写入内容
-	content := response.Content()
-	if _, err := file.Write(content); err != nil {
-		return fmt.Errorf("failed to write to file: %w", err)
+	if c.Append {
+		flags = os.O_CREATE | os.O_WRONLY | os.O_APPEND
 	}
-
-	return nil
+	return flags, true, nil
 }
 
 // determineOutputPath 确定输出文件路径
@@ -418,10 +492,13 @@ func New() *CURL {
 	u.TLSHandshakeTimeout = 0    // 0 表示不设置，使用系统默认
 
 	u.LimitRate = ""                // 默认不限速
-	u.MaxRedirs = -1                // 默认无限制重定向
+	u.MaxRedirs = -1                // 未指定 --max-redirs，跟随重定向时使用 curl 默认的 30 次
 	u.HTTP2 = false                 // 默认不强制HTTP/2
 	u.HTTPVersion = HTTPVersionAuto // 默认自动选择协议版本
 	u.FollowRedirect = false        // 默认不跟随重定向（与curl默认行为一致）
+	u.TCPNoDelay = true             // 与curl一致，默认开启 TCP_NODELAY
+	u.KeepAlive = true              // 与curl一致，默认复用连接
+	u.TCPKeepAlive = true           // 与curl一致，默认开启TCP保活探测
 	u.CACert = ""                   // 默认无自定义CA证书
 	u.ClientCert = ""               // 默认无客户端证书
 	u.ClientKey = ""                // 默认无客户端私钥
@@ -447,38 +524,37 @@ func (curl *CURL) String() string {
 	return ""
 }
 
-// Execute 直接执行curlbash，并支持脚本选项 --fail 和 --write-out
-// Execute 直接执行curlbash，并支持脚本选项 --fail 和 --write-out
+// Execute 直接执行curlbash，并支持脚本选项 --fail、--write-out 和 --retry
 func Execute(curlbash string) (*requests.Response, error) {
 	c, err := ParseBash(curlbash)
 	if err != nil {
 		return nil, err
 	}
-	start := time.Now()
-	resp, err := c.CreateRequest(nil).Execute()
-	duration := time.Since(start)
+	// -w 的输出在 Execute 中完成
+	resp, err := c.Execute()
 	// 脚本错误处理
 	if c.FailOnError && resp != nil && resp.GetStatusCode() >= 400 {
 		return resp, fmt.Errorf("HTTP error: %d", resp.GetStatusCode())
 	}
-	// 格式化输出
-	if c.WriteOutFormat != "" && resp != nil {
-		out := c.WriteOutFormat
-		// 支持 %{http_code}
-		out = strings.ReplaceAll(out, "%{http_code}", fmt.Sprintf("%d", resp.GetStatusCode()))
-		// 支持 %{time_total}
-		out = strings.ReplaceAll(out, "%{time_total}", fmt.Sprintf("%.3f", duration.Seconds()))
-		fmt.Print(out)
-	}
 	return resp, err
 }
 
 // CreateSession 创建Session
 func (curl *CURL) CreateSession() *requests.Session {
 	ses := requests.NewSession()
+	// 各项网络配置都依赖 Session 内部的 Transport 和 Client，无法访问时让请求返回错误，而不是静默忽略这些选项
+	if sessionTransport(ses) == nil || sessionClient(ses) == nil {
+		ses.AddMiddleware(errorMiddleware{err: errSessionInternals})
+		return ses
+	}
 
 	// 设置基本配置
 	ses.SetHeader(curl.Header)
+	// 请求了压缩编码时，响应体由 Content-Encoding 决定是否解码（见 contentDecoder），requests 不能再猜测
+	// 没有声明编码的数据是否被压缩；其他情况保持 requests 的默认行为
+	if curl.Compressed || curl.Header.Get("Accept-Encoding") != "" {
+		ses.Config().SetDecompressNoAccept(false)
+	}
 	ses.SetCookies(curl.ParsedURL, curl.Cookies)
 
 	// 设置总超时
@@ -492,9 +568,7 @@ func (curl *CURL) CreateSession() *requests.Session {
 		case "basic":
 			ses.Config().SetBasicAuth(curl.AuthV2.Username, curl.AuthV2.Password)
 		case "digest":
-			// Digest认证需要特殊处理
-			ses.Config().SetBasicAuth(curl.AuthV2.Username, curl.AuthV2.Password)
-			// TODO: 在requests库中实现真正的Digest认证支持
+			// Digest认证需要挑战-响应流程，在 configureDigest 中包装 Transport
 		case "bearer":
 			// Bearer认证通过Header设置
 			authHeader := make(http.Header)
@@ -511,95 +585,55 @@ func (curl *CURL) CreateSession() *requests.Session {
 		ses.Config().SetInsecure(curl.Insecure)
 	}
 
-	// 设置代理（包括SOCKS5）及可选的代理认证
-	if curl.Proxy != "" {
-		proxyURL := curl.Proxy
-		// 如果提供了代理用户信息，注入到URL中
-		if curl.ProxyUser != "" {
-			if u, err := url.Parse(curl.Proxy); err == nil {
-				u.User = url.UserPassword(curl.ProxyUser, curl.ProxyPassword)
-				proxyURL = u.String()
-			}
-		}
-		ses.Config().SetProxy(proxyURL)
-	}
+	// 设置拨号器（--resolve 等网络选项）
+	curl.configureTransport(ses)
 
-	// 设置连接超时（如果指定了）
-	if curl.ConnectTimeout > 0 {
-		// 目前先使用总超时作为连接超时的替代方案
-		// 理想情况下应该在requests库中添加专门的SetConnectTimeout方法
-		// TODO: 在requests库中实现真正的连接超时设置
-		ses.Config().SetTimeout(curl.ConnectTimeout)
-	}
+	// 连接超时和DNS超时由拨号器处理，这里只设置TLS握手超时
+	curl.configureTimeouts(ses)
 
 	// 设置重定向策略
-	if curl.FollowRedirect {
-		// 设置最大重定向次数
-		maxRedirs := curl.MaxRedirs
-		if maxRedirs < 0 {
-			maxRedirs = 30 // 默认值
-		}
-		// TODO: 在requests库中添加SetRedirectPolicy方法
-		// ses.Config().SetRedirectPolicy(maxRedirs)
-		// 目前先用注释标记这个功能需要实现
-	}
+	curl.configureRedirect(ses)
+
+	// 设置TLS/SSL证书配置
+	curl.configureTLS(ses)
+
+	// 设置 --trace 追踪（需要在 HTTP/1.0 改写请求行之前接入连接）
+	curl.configureTrace(ses)
 
 	// 设置HTTP协议版本控制
 	curl.configureHTTPVersion(ses)
 
-	// 设置TLS/SSL证书配置
-	if curl.CACert != "" {
-		// TODO: 在requests库中添加SetCACert方法
-		// ses.Config().SetCACert(curl.CACert)
-	}
+	// 设置代理（HTTP/HTTPS/SOCKS、--noproxy 和代理环境变量）
+	curl.configureProxy(ses)
 
-	if curl.ClientCert != "" && curl.ClientKey != "" {
-		// TODO: 在requests库中添加SetClientCerts方法
-		// ses.Config().SetClientCerts(curl.ClientCert, curl.ClientKey)
-	}
+	// 设置上传和下载限速
+	curl.configureRateLimit(ses)
 
-	return ses
-}
+	// 设置响应体大小限制
+	curl.configureMaxFileSize(ses)
 
-// configureHTTPVersion 配置HTTP协议版本
-func (curl *CURL) configureHTTPVersion(ses *requests.Session) {
-	// 根据协议版本设置相应的配置
-	switch curl.HTTPVersion {
-	case HTTPVersion10:
-		// 强制使用 HTTP/1.0
-		// 通过设置TLS配置来限制协议版本
-		curl.setHTTPVersionInTLS(ses, "1.0")
-	case HTTPVersion11:
-		// 强制使用 HTTP/1.1
-		curl.setHTTPVersionInTLS(ses, "1.1")
-	case HTTPVersion2:
-		// 强制使用 HTTP/2
-		curl.HTTP2 = true // 保持向后兼容
-		curl.setHTTPVersionInTLS(ses, "2")
-	case HTTPVersionAuto:
-		// 自动选择，如果设置了HTTP2标志则使用HTTP/2
-		if curl.HTTP2 {
-			curl.setHTTPVersionInTLS(ses, "2")
-		}
-		// 否则让库自动选择
-	}
-}
-
-// setHTTPVersionInTLS 通过TLS配置设置HTTP版本
-func (curl *CURL) setHTTPVersionInTLS(ses *requests.Session, version string) {
-	// 这是一个内部方法，用于设置协议版本偏好
-	// 实际的协议协商由Go的http包和TLS处理
-	// 我们主要是设置一些标志来影响协议选择
+	// 设置Digest认证（包装在最外层的 Transport 上）
+	curl.configureDigest(ses)
 
-	// 记录协议版本偏好，供调试和日志使用
-	curl.setHTTPVersionPreference(version)
+	return ses
 }
 
-// setHTTPVersionPreference 设置协议版本偏好（内部方法）
+// setHTTPVersionPreference 根据版本字符串设置协议版本，无法识别的版本会被忽略
 func (curl *CURL) setHTTPVersionPreference(version string) {
-	// 这个方法主要用于记录用户的协议版本偏好
-	// 实际的HTTP版本控制在Go标准库层面处理
-	// 我们主要确保正确的配置传递到底层
+	switch strings.TrimPrefix(strings.ToUpper(version), "HTTP/") {
+	case "1.0":
+		curl.HTTP2 = false
+		curl.HTTPVersion = HTTPVersion10
+	case "1.1":
+		curl.HTTP2 = false
+		curl.HTTPVersion = HTTPVersion11
+	case "2":
+		curl.HTTP2 = true
+		curl.HTTPVersion = HTTPVersion2
+	case "2-PRIOR-KNOWLEDGE":
+		curl.HTTP2 = true
+		curl.HTTPVersion = HTTPVersion2PriorKnowledge
+	}
 }
 
 // CreateRequest 根据Session 创建Request
@@ -631,14 +665,24 @@ func (curl *CURL) CreateRequest(ses *requests.Session) *requests.Request {
 		panic("curl.Method is not UNKNOWN")
 	}
 
-	wf.SetContentType(curl.ContentType)
+	if curl.ContentType != "" {
+		wf.SetContentType(curl.ContentType)
+	}
+
+	// -C 断点续传，从偏移处开始请求剩余部分
+	if curl.ContinueAt != 0 {
+		if offset, err := curl.resumeOffset(); err == nil && offset > 0 {
+			wf.SetHeader("Range", fmt.Sprintf("bytes=%d-", offset))
+		}
+	}
 
 	// 根据Body类型设置不同的请求体
 	if curl.Body != nil {
 		switch curl.Body.Type {
 		case "raw":
 			if buf, ok := curl.Body.Content.(*bytes.Buffer); ok {
-				wf.SetBody(buf)
+				// 不消耗 curl.Body，保证同一个 CURL 可以多次创建请求（例如重试）
+				wf.SetBody(bytes.NewReader(buf.Bytes()))
 			}
 		case "multipart":
 			if fields, ok := curl.Body.Content.([]*FormField); ok {
@@ -888,9 +932,73 @@ func (c *CURL) debugNetworkConfig(b *strings.Builder) {
 		b.WriteString("  HTTP Version: Auto\n")
 	}
 
+	if c.UnixSocket != "" {
+		if c.AbstractUnixSocket {
+			b.WriteString(fmt.Sprintf("  Unix Socket: @%s (abstract)\n", c.UnixSocket))
+		} else {
+			b.WriteString(fmt.Sprintf("  Unix Socket: %s\n", c.UnixSocket))
+		}
+	}
+	if c.Interface != "" {
+		b.WriteString(fmt.Sprintf("  Interface: %s\n", c.Interface))
+	}
+	if c.LocalPort != "" {
+		b.WriteString(fmt.Sprintf("  Local Port: %s\n", c.LocalPort))
+	}
+	if c.IPVersion != 0 {
+		b.WriteString(fmt.Sprintf("  IP Version: IPv%d only\n", c.IPVersion))
+	}
+	if !c.KeepAlive {
+		b.WriteString("  Connection Reuse: DISABLED\n")
+	}
+	if !c.TCPKeepAlive {
+		b.WriteString("  TCP Keepalive: DISABLED\n")
+	} else if c.KeepAliveTime > 0 {
+		b.WriteString(fmt.Sprintf("  TCP Keepalive Time: %v\n", c.KeepAliveTime))
+	}
+	if !c.TCPNoDelay {
+		b.WriteString("  TCP NoDelay: DISABLED\n")
+	}
+	if c.TCPFastOpen {
+		b.WriteString("  TCP Fast Open: YES\n")
+	}
+	if c.LimitRate != "" {
+		b.WriteString(fmt.Sprintf("  Limit Rate: %s\n", c.LimitRate))
+	}
+	if c.MaxFileSize > 0 {
+		b.WriteString(fmt.Sprintf("  Max File Size: %d bytes\n", c.MaxFileSize))
+	}
+
 	if c.Proxy != "" {
 		b.WriteString(fmt.Sprintf("  Proxy: %s\n", c.Proxy))
 	}
+	if c.PreProxy != "" {
+		b.WriteString(fmt.Sprintf("  Pre-Proxy: %s\n", c.PreProxy))
+	}
+	if c.NoProxy != "" {
+		b.WriteString(fmt.Sprintf("  No Proxy: %s\n", c.NoProxy))
+	}
+	if c.ProxyAuth != "" {
+		b.WriteString(fmt.Sprintf("  Proxy Auth: %s\n", c.ProxyAuth))
+	}
+	if c.ProxyTunnel {
+		b.WriteString("  Proxy Tunnel: YES\n")
+	}
+	if len(c.ProxyHeader) > 0 {
+		b.WriteString(fmt.Sprintf("  Proxy Headers: %d\n", len(c.ProxyHeader)))
+	}
+	if c.ProxyInsecure {
+		b.WriteString("  Proxy SSL Verification: DISABLED\n")
+	}
+	if c.ProxyCACert != "" {
+		b.WriteString(fmt.Sprintf("  Proxy CA Certificate: %s\n", c.ProxyCACert))
+	}
+	if c.ProxyCAPath != "" {
+		b.WriteString(fmt.Sprintf("  Proxy CA Path: %s\n", c.ProxyCAPath))
+	}
+	if c.ProxyPinnedPubKey != "" {
+		b.WriteString(fmt.Sprintf("  Proxy Pinned Public Key: %s\n", c.ProxyPinnedPubKey))
+	}
 	if c.Insecure {
 		b.WriteString("  SSL Verification: DISABLED\n")
 	}
@@ -898,17 +1006,26 @@ func (c *CURL) debugNetworkConfig(b *strings.Builder) {
 
 // debugSSLConfig 输出SSL/TLS配置信息
 func (c *CURL) debugSSLConfig(b *strings.Builder) {
-	if c.CACert != "" || c.ClientCert != "" || c.ClientKey != "" {
+	if c.hasTLSOptions() {
 		b.WriteString("SSL/TLS Configuration:\n")
 		if c.CACert != "" {
 			b.WriteString(fmt.Sprintf("  CA Certificate: %s\n", c.CACert))
 		}
+		if c.CAPath != "" {
+			b.WriteString(fmt.Sprintf("  CA Path: %s\n", c.CAPath))
+		}
 		if c.ClientCert != "" {
 			b.WriteString(fmt.Sprintf("  Client Certificate: %s\n", c.ClientCert))
 		}
 		if c.ClientKey != "" {
 			b.WriteString(fmt.Sprintf("  Client Key: %s\n", c.ClientKey))
 		}
+		if c.CRLFile != "" {
+			b.WriteString(fmt.Sprintf("  CRL File: %s\n", c.CRLFile))
+		}
+		if c.PinnedPubKey != "" {
+			b.WriteString(fmt.Sprintf("  Pinned Public Key: %s\n", c.PinnedPubKey))
+		}
 	}
 }
 
@@ -917,11 +1034,17 @@ func (c *CURL) debugRedirectConfig(b *strings.Builder) {
 	if c.FollowRedirect {
 		b.WriteString("Redirect Configuration:\n")
 		b.WriteString("  Follow Redirects: YES\n")
-		if c.MaxRedirs >= 0 {
-			b.WriteString(fmt.Sprintf("  Max Redirects: %d\n", c.MaxRedirs))
+		if limit := c.redirectLimit(); limit >= 0 {
+			b.WriteString(fmt.Sprintf("  Max Redirects: %d\n", limit))
 		} else {
 			b.WriteString("  Max Redirects: unlimited\n")
 		}
+		if c.LocationTrusted {
+			b.WriteString("  Location Trusted: YES\n")
+		}
+		if c.Post301 || c.Post302 || c.Post303 {
+			b.WriteString(fmt.Sprintf("  Keep POST: 301=%t 302=%t 303=%t\n", c.Post301, c.Post302, c.Post303))
+		}
 	}
 }
 
@@ -943,6 +1066,20 @@ func (c *CURL) debugFlags(b *strings.Builder) {
 	if len(debugFlags) > 0 {
 		b.WriteString(fmt.Sprintf("Debug Flags: %s\n", strings.Join(debugFlags, ", ")))
 	}
+	if c.DumpHeader != "" {
+		b.WriteString(fmt.Sprintf("Dump Header: %s\n", c.DumpHeader))
+	}
+	if c.Trace {
+		mode := "hex"
+		if c.TraceASCII {
+			mode = "ascii"
+		}
+		target := c.TraceFile
+		if target == "" {
+			target = "-"
+		}
+		b.WriteString(fmt.Sprintf("Trace: %s (%s)\n", target, mode))
+	}
 }
 
 // debugFileOutput 输出文件输出配置信息
@@ -966,6 +1103,8 @@ func (c *CURL) debugFileOutput(b *strings.Builder) {
 		}
 		if c.ContinueAt > 0 {
 			b.WriteString(fmt.Sprintf("  Continue At: %d bytes\n", c.ContinueAt))
+		} else if c.ContinueAt < 0 {
+			b.WriteString("  Continue At: auto (size of output file)\n")
 		}
 	}
 }
@@ -1002,7 +1141,32 @@ func (c *CURL) Summary() string {
 	return strings.Join(parts, " | ")
 }
 
-// Verbose 返回详细的执行信息（模拟 curl -v 的输出）
+// previewProto 返回预览请求行中的协议，与 configureHTTPVersion 实际使用的协议一致
+func (c *CURL) previewProto() string {
+	https := c.ParsedURL != nil && c.ParsedURL.Scheme == "https"
+	switch c.httpVersion() {
+	case HTTPVersion10:
+		return "HTTP/1.0"
+	case HTTPVersion11:
+		return "HTTP/1.1"
+	case HTTPVersion2PriorKnowledge:
+		return "HTTP/2"
+	case HTTPVersion2:
+		// 明文连接不会升级到 h2c
+		if https {
+			return "HTTP/2"
+		}
+		return "HTTP/1.1"
+	}
+	// --trace 在自动选择时只使用 HTTP/1.1，见 configureTrace
+	if https && !c.Trace {
+		return "HTTP/1.1 or HTTP/2 (ALPN)"
+	}
+	return "HTTP/1.1"
+}
+
+// VerboseInfo 根据解析结果预览将要发送的请求（执行前，不涉及网络）
+// 执行时真实的 curl -v 输出由 Verbose 选项写入 Stderr
 func (c *CURL) VerboseInfo() string {
 	var b strings.Builder
 
@@ -1041,14 +1205,9 @@ func (c *CURL) VerboseInfo() string {
 			}
 		}
 
-		b.WriteString(fmt.Sprintf("* Trying %s...\n", c.ParsedURL.Host))
-		b.WriteString(fmt.Sprintf("* Connected to %s port %s\n", c.ParsedURL.Hostname(), c.ParsedURL.Port()))
-
-		if c.ParsedURL.Scheme == "https" {
-			b.WriteString("* SSL connection using TLS\n")
-			if c.Insecure {
-				b.WriteString("* WARNING: SSL verification disabled!\n")
-			}
+		b.WriteString("* Request preview, no connection has been made (use -v when executing for real events)\n")
+		if c.ParsedURL.Scheme == "https" && c.Insecure {
+			b.WriteString("* WARNING: SSL verification disabled!\n")
 		}
 
 		// 请求行
@@ -1059,7 +1218,7 @@ func (c *CURL) VerboseInfo() string {
 		if c.ParsedURL.RawQuery != "" {
 			path += "?" + c.ParsedURL.RawQuery
 		}
-		b.WriteString(fmt.Sprintf("> %s %s HTTP/1.1\n", c.Method, path))
+		b.WriteString(fmt.Sprintf("> %s %s %s\n", c.Method, path, c.previewProto()))
 		b.WriteString(fmt.Sprintf("> Host: %s\n", c.ParsedURL.Host))
 
 		// 请求头
@@ -5,6 +5,8 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+
+	"github.com/474420502/requests"
 )
 
 // isValidURL 实现更严格的URL验证
@@ -136,5 +138,49 @@ func buildFromArgs(args []string) (*CURL, error) {
 		}
 	}
 
+	// 3. -G/--get 模式下，将请求体数据移动到URL查询参数中
+	if curl.GetMode {
+		curl.moveBodyToQuery()
+	}
+
 	return curl, nil
 }
+
+// moveBodyToQuery 将 -d/--data-urlencode 累积的数据追加到URL查询参数并清空请求体
+// 已有的查询参数会被保留，请求方法（GET 或 -I 的 HEAD）保持不变
+func (c *CURL) moveBodyToQuery() {
+	if c.Body == nil || c.Body.Type != "raw" || c.Body.Len() == 0 {
+		return
+	}
+
+	// 与 curl 一致，数据附加到查询字符串时不再重新编码，--data-urlencode 的部分在解析时已经编码；
+	// 只转义不能出现在请求行中的字节（空格、控制字符等），已经编码的数据保持不变
+	query := escapeQueryBytes(c.Body.String())
+	if c.ParsedURL.RawQuery != "" {
+		c.ParsedURL.RawQuery += "&" + query
+	} else {
+		c.ParsedURL.RawQuery = query
+	}
+	c.setRawBody(nil)
+
+	// 数据不再作为请求体发送，去掉 -d 隐式添加的 Content-Type
+	if c.ContentType == requests.TypeURLENCODED {
+		c.Header.Del("Content-Type")
+		c.ContentType = ""
+	}
+}
+
+// escapeQueryBytes 对查询字符串中不合法的字节做百分号编码：空格、控制字符、非 ASCII 字节和 "#"
+// 其他字符（包括 "%"）原样保留
+func escapeQueryBytes(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ch <= ' ' || ch >= 0x7f || ch == '#' {
+			fmt.Fprintf(&b, "%%%02X", ch)
+			continue
+		}
+		b.WriteByte(ch)
+	}
+	return b.String()
+}
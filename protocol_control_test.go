@@ -1,7 +1,15 @@
 package gcurl
 
 import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
 func TestHTTPVersionControl(t *testing.T) {
@@ -47,6 +55,13 @@ func TestHTTPVersionControl(t *testing.T) {
 			expectedHTTP2:   true,
 			wantErr:         false,
 		},
+		{
+			name:            "HTTP/2 prior knowledge",
+			curlCommand:     `curl --http2-prior-knowledge http://example.com/`,
+			expectedVersion: HTTPVersion2PriorKnowledge,
+			expectedHTTP2:   true,
+			wantErr:         false,
+		},
 		{
 			name:            "HTTP/1.1 overrides HTTP/2",
 			curlCommand:     `curl --http2 --http1.1 https://httpbin.org/get`,
@@ -91,6 +106,7 @@ func TestHTTPVersionStringRepresentation(t *testing.T) {
 		{HTTPVersion10, "HTTP/1.0"},
 		{HTTPVersion11, "HTTP/1.1"},
 		{HTTPVersion2, "HTTP/2"},
+		{HTTPVersion2PriorKnowledge, "HTTP/2 (prior knowledge)"},
 	}
 
 	for _, tt := range tests {
@@ -260,6 +276,141 @@ func TestHTTPVersionComplexScenarios(t *testing.T) {
 	}
 }
 
+// TestHTTPVersionOnTheWire 验证选择的协议版本确实用于请求，并体现在响应上
+func TestHTTPVersionOnTheWire(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Proto", r.Proto)
+		if r.TLS != nil {
+			w.Header().Set("X-ALPN", r.TLS.NegotiatedProtocol)
+		}
+	})
+
+	plain := httptest.NewServer(handler)
+	defer plain.Close()
+
+	tlsServer := httptest.NewUnstartedServer(handler)
+	tlsServer.EnableHTTP2 = true
+	tlsServer.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	tlsServer.StartTLS()
+	defer tlsServer.Close()
+
+	h2cServer := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer h2cServer.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	writePEM(t, caFile, "CERTIFICATE", tlsServer.Certificate().Raw)
+
+	tests := []struct {
+		name        string
+		command     string
+		serverProto string
+		alpn        string
+		respProto   string
+	}{
+		{"Auto TLS negotiates h2", `curl --cacert ` + caFile + ` ` + tlsServer.URL, "HTTP/2.0", "h2", "HTTP/2.0"},
+		{"Auto insecure TLS negotiates h2", `curl -k ` + tlsServer.URL, "HTTP/2.0", "h2", "HTTP/2.0"},
+		{"Auto plain", `curl ` + plain.URL, "HTTP/1.1", "", "HTTP/1.1"},
+		{"HTTP/1.0 plain", `curl --http1.0 ` + plain.URL, "HTTP/1.0", "", "HTTP/1.0"},
+		{"HTTP/1.0 TLS", `curl -k --http1.0 ` + tlsServer.URL, "HTTP/1.0", "http/1.1", "HTTP/1.0"},
+		{"HTTP/1.1 TLS", `curl -k --http1.1 ` + tlsServer.URL, "HTTP/1.1", "http/1.1", "HTTP/1.1"},
+		{"HTTP/2 TLS", `curl -k --http2 ` + tlsServer.URL, "HTTP/2.0", "h2", "HTTP/2.0"},
+		{"HTTP/2 prior knowledge", `curl --http2-prior-knowledge ` + h2cServer.URL, "HTTP/2.0", "", "HTTP/2.0"},
+		{"HTTP/2 prior knowledge TLS", `curl -k --http2-prior-knowledge ` + tlsServer.URL, "HTTP/2.0", "h2", "HTTP/2.0"},
+		{"HTTP/2 plain falls back", `curl --http2 ` + plain.URL, "HTTP/1.1", "", "HTTP/1.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(tt.command)
+			if err != nil {
+				t.Fatalf("Parse() error: %v", err)
+			}
+
+			resp, err := c.Request().Execute()
+			if err != nil {
+				t.Fatalf("Execute() error: %v", err)
+			}
+
+			header := resp.GetHeader()
+			if got := header.Get("X-Proto"); got != tt.serverProto {
+				t.Errorf("server saw %s, want %s", got, tt.serverProto)
+			}
+			if got := header.Get("X-ALPN"); got != tt.alpn {
+				t.Errorf("ALPN = %q, want %q", got, tt.alpn)
+			}
+			if got := resp.GetResponse().Proto; got != tt.respProto {
+				t.Errorf("response proto = %s, want %s", got, tt.respProto)
+			}
+		})
+	}
+}
+
+// TestHTTP2RequiresALPN 验证 --http2 在服务端不支持 h2 时失败，而不是静默使用 HTTP/1.1
+func TestHTTP2RequiresALPN(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	for _, option := range []string{"--http2", "--http2-prior-knowledge"} {
+		c, err := Parse(`curl -k ` + option + ` ` + srv.URL)
+		if err != nil {
+			t.Fatalf("Parse() error: %v", err)
+		}
+		_, err = c.Request().Execute()
+		if err == nil || !strings.Contains(err.Error(), "HTTP/2 was requested") {
+			t.Errorf("%s: expected ALPN error, got %v", option, err)
+		}
+	}
+
+	// 不指定版本时正常回退到 HTTP/1.1
+	c, err := Parse(`curl -k ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	resp, err := c.Request().Execute()
+	if err != nil {
+		t.Fatalf("Execute() error: %v", err)
+	}
+	if got := resp.GetResponse().Proto; got != "HTTP/1.1" {
+		t.Errorf("response proto = %s, want HTTP/1.1", got)
+	}
+}
+
+// TestHTTPVersionPreview 验证预览中的协议与实际使用的协议一致，且创建 Session 不会修改解析结果
+func TestHTTPVersionPreview(t *testing.T) {
+	tests := []struct {
+		command string
+		want    string
+	}{
+		{`curl https://example.com/`, "HTTP/1.1 or HTTP/2 (ALPN)"},
+		{`curl http://example.com/`, "HTTP/1.1"},
+		{`curl --http2 https://example.com/`, "HTTP/2"},
+		{`curl --http2 http://example.com/`, "HTTP/1.1"},
+		{`curl --http2-prior-knowledge http://example.com/`, "HTTP/2"},
+		{`curl --http1.1 https://example.com/`, "HTTP/1.1"},
+		{`curl --trace - https://example.com/`, "HTTP/1.1"},
+		{`curl --trace - --http2 https://example.com/`, "HTTP/2"},
+	}
+	for _, tt := range tests {
+		c, err := Parse(tt.command)
+		if err != nil {
+			t.Fatalf("Parse(%s) error: %v", tt.command, err)
+		}
+		if got := c.previewProto(); got != tt.want {
+			t.Errorf("%s: previewProto() = %q, want %q", tt.command, got, tt.want)
+		}
+	}
+
+	c, err := Parse(`curl https://example.com/`)
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	c.HTTPVersion = HTTPVersion2
+	c.CreateSession()
+	if c.HTTP2 {
+		t.Error("CreateSession() should not change the parsed HTTP2 field")
+	}
+}
+
 // containsString 检查字符串是否包含子字符串
 func containsString(str, substr string) bool {
 	return len(str) >= len(substr) &&
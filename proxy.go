@@ -0,0 +1,608 @@
+package gcurl
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/474420502/requests"
+)
+
+// ExitCodeResolveProxy 与 curl 无法解析代理主机名时的退出码一致
+const ExitCodeResolveProxy = 5
+
+// ExitCodeProxy 与 curl 代理握手失败时的退出码一致
+const ExitCodeProxy = 97
+
+// ProxyError 表示无法通过代理建立连接
+// 可以通过 errors.As 从 Execute 返回的错误中取出
+type ProxyError struct {
+	Proxy      string // 代理的 host:port
+	StatusCode int    // CONNECT 响应的状态码，0 表示不是 CONNECT 被拒绝
+	Err        error
+
+	header http.Header // CONNECT 响应的头部，用于应答 407 认证挑战
+}
+
+// Error 实现 error
+func (e *ProxyError) Error() string {
+	return fmt.Sprintf("proxy %s: %v", e.Proxy, e.Err)
+}
+
+// Unwrap 返回底层错误
+func (e *ProxyError) Unwrap() error {
+	return e.Err
+}
+
+// ExitCode 返回对应的 curl 退出码
+func (e *ProxyError) ExitCode() int {
+	var dnsErr *net.DNSError
+	switch {
+	case errors.As(e.Err, &dnsErr):
+		return ExitCodeResolveProxy
+	case e.StatusCode != 0:
+		// 与 curl 一致，CONNECT 被拒绝属于接收数据失败
+		return 56
+	}
+	return ExitCodeProxy
+}
+
+// proxyContextKey 是请求 context 中 proxyRoute 的键
+type proxyContextKey struct{}
+
+// proxyRoute 描述一个请求经过代理的方式
+type proxyRoute struct {
+	proxy     *url.URL    // 代理地址，scheme 和端口已经补全
+	preProxy  *url.URL    // --preproxy，连接代理之前先经过的 SOCKS 代理
+	tunnel    bool        // 为 true 时通过 SOCKS 或 CONNECT 建立到目标的隧道，否则把请求转发给 HTTP 代理
+	header    http.Header // CONNECT 请求的头部
+	tlsConfig *tls.Config // 连接 HTTPS 代理使用的 TLS 配置
+	auth      *proxyAuth  // HTTP 代理的认证状态，没有凭据时为 nil
+}
+
+// proxyFromContext 返回 ctx 中的 proxyRoute，不使用代理时返回 nil
+func proxyFromContext(ctx context.Context) *proxyRoute {
+	route, _ := ctx.Value(proxyContextKey{}).(*proxyRoute)
+	return route
+}
+
+// isSocksProxy 判断代理是否为 SOCKS 代理
+func isSocksProxy(scheme string) bool {
+	switch scheme {
+	case "socks4", "socks4a", "socks5", "socks5h":
+		return true
+	}
+	return false
+}
+
+// parseProxy 解析代理地址，没有 scheme 时使用 defaultScheme
+// 与 curl 一致，没有端口时使用 1080，HTTPS 代理使用 443
+func parseProxy(raw, defaultScheme string) (*url.URL, error) {
+	if !strings.Contains(raw, "://") {
+		raw = defaultScheme + "://" + raw
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy: %w", err)
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	if u.Scheme != "http" && u.Scheme != "https" && !isSocksProxy(u.Scheme) {
+		return nil, fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("missing host in proxy %s", u.Redacted())
+	}
+	if u.Port() == "" {
+		port := "1080"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+		u.Host = net.JoinHostPort(u.Hostname(), port)
+	}
+	return &url.URL{Scheme: u.Scheme, User: u.User, Host: u.Host}, nil
+}
+
+// getenv 返回第一个不为空的环境变量
+func getenv(names ...string) string {
+	for _, name := range names {
+		if value := os.Getenv(name); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// proxyFromEnvironment 按照 curl 的规则读取 scheme 对应的代理环境变量
+// 与 curl 一致，http_proxy 只读取小写形式，避免 CGI 环境中伪造的 Proxy 请求头（HTTP_PROXY）生效
+func proxyFromEnvironment(scheme string) string {
+	name := strings.ToLower(scheme) + "_proxy"
+	if scheme == "http" {
+		return getenv(name, "all_proxy", "ALL_PROXY")
+	}
+	return getenv(name, strings.ToUpper(name), "all_proxy", "ALL_PROXY")
+}
+
+// matchNoProxy 判断 host 是否在 --noproxy/no_proxy 列表中
+//   - "*" 匹配所有主机
+//   - 域名匹配自身及其子域名，开头的 "." 可以省略
+//   - IP 地址精确匹配，也可以使用 CIDR 表示网段
+func matchNoProxy(list, host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	ip := net.ParseIP(host)
+	for _, entry := range strings.Split(list, ",") {
+		entry = strings.ToLower(strings.TrimSpace(entry))
+		switch {
+		case entry == "":
+			continue
+		case entry == "*":
+			return true
+		case ip != nil:
+			if _, network, err := net.ParseCIDR(entry); err == nil {
+				if network.Contains(ip) {
+					return true
+				}
+				continue
+			}
+			if other := net.ParseIP(strings.Trim(entry, "[]")); other != nil && other.Equal(ip) {
+				return true
+			}
+		default:
+			entry = strings.TrimPrefix(strings.TrimSuffix(entry, "."), ".")
+			if host == entry || strings.HasSuffix(host, "."+entry) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// proxyTransport 为每个请求选择代理，并通过 context 把结果交给 Transport.Proxy 和拨号器
+// 环境变量在每个请求时读取，重定向到其他协议或主机时会重新选择
+type proxyTransport struct {
+	base      http.RoundTripper
+	curl      *CURL
+	proxy     *url.URL // -x/--socks* 指定的代理，nil 时读取环境变量
+	preProxy  *url.URL
+	tlsConfig *tls.Config
+
+	mu    sync.Mutex
+	auths map[string]*proxyAuth // 按代理地址和凭据保存的认证状态
+}
+
+// newProxyTransport 解析命令行指定的代理
+func (curl *CURL) newProxyTransport(base http.RoundTripper) (*proxyTransport, error) {
+	tlsConfig, err := curl.buildProxyTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	t := &proxyTransport{base: base, curl: curl, tlsConfig: tlsConfig, auths: make(map[string]*proxyAuth)}
+	if curl.Proxy != "" {
+		proxy, err := parseProxy(curl.Proxy, "http")
+		if err != nil {
+			return nil, err
+		}
+		t.proxy = proxy
+	}
+	if curl.PreProxy != "" {
+		// 与 curl 一致，--preproxy 没有 scheme 时使用 SOCKS4
+		preProxy, err := parseProxy(curl.PreProxy, "socks4")
+		if err != nil {
+			return nil, err
+		}
+		if !isSocksProxy(preProxy.Scheme) {
+			return nil, fmt.Errorf("--preproxy must be a SOCKS proxy, got %s", preProxy.Scheme)
+		}
+		t.preProxy = preProxy
+	}
+	return t, nil
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *proxyTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	route, err := t.route(req.URL)
+	if err != nil {
+		if req.Body != nil {
+			req.Body.Close()
+		}
+		return nil, err
+	}
+	if route == nil {
+		return t.base.RoundTrip(req)
+	}
+	req = req.WithContext(context.WithValue(req.Context(), proxyContextKey{}, route))
+	if route.tunnel {
+		return t.base.RoundTrip(req)
+	}
+	return t.forward(req, route)
+}
+
+// forward 把请求转发给 HTTP 代理，收到 407 挑战时计算 Proxy-Authorization 后重发
+func (t *proxyTransport) forward(req *http.Request, route *proxyRoute) (*http.Response, error) {
+	if len(t.curl.ProxyHeader) > 0 {
+		// 转发模式下请求直接发给代理，--proxy-header 加在请求本身上
+		req.Header = req.Header.Clone()
+		applyProxyHeader(req.Header, t.curl.ProxyHeader)
+	}
+	if route.auth == nil {
+		return t.base.RoundTrip(req)
+	}
+
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	// 转发模式的请求行使用绝对 URI，Digest 的 uri 与之一致
+	uri := req.URL.Scheme + "://" + req.URL.Host + req.URL.RequestURI()
+
+	// 最多重发两次：一次应答初始挑战，一次应答 stale nonce
+	for attempt := 0; ; attempt++ {
+		r := cloneRequest(req, body, "")
+		sent := r.Header.Get("Proxy-Authorization") != ""
+		if !sent {
+			if authorization := route.auth.authorization(req.Method, uri); authorization != "" {
+				route.auth.logScheme(req.Context())
+				r.Header.Set("Proxy-Authorization", authorization)
+				sent = true
+			}
+		}
+		resp, err := t.base.RoundTrip(r)
+		if err != nil || resp.StatusCode != http.StatusProxyAuthRequired || attempt >= 2 ||
+			!route.auth.challenge(resp.Header, sent) {
+			return resp, err
+		}
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+}
+
+// authFor 返回代理 proxy 的认证状态，同一个代理和凭据在 Session 中只创建一次
+func (t *proxyTransport) authFor(proxy *url.URL) *proxyAuth {
+	key := proxy.String()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	auth, ok := t.auths[key]
+	if !ok {
+		auth = newProxyAuth(t.curl.ProxyAuth, proxy.User)
+		t.auths[key] = auth
+	}
+	return auth
+}
+
+// route 返回请求 target 使用的代理，不使用代理时返回 nil
+func (t *proxyTransport) route(target *url.URL) (*proxyRoute, error) {
+	proxy, err := t.proxyFor(target)
+	if proxy == nil || err != nil {
+		return nil, err
+	}
+
+	route := &proxyRoute{
+		proxy:     proxy,
+		preProxy:  t.preProxy,
+		header:    make(http.Header),
+		tlsConfig: t.tlsConfig,
+	}
+	// HTTP 代理只转发明文 HTTP/1 请求，HTTPS 和 h2c 都需要 CONNECT 隧道，-p 时所有请求都使用隧道
+	route.tunnel = isSocksProxy(proxy.Scheme) || target.Scheme != "http" || t.curl.ProxyTunnel ||
+		t.curl.HTTPVersion == HTTPVersion2PriorKnowledge
+	if ua := t.curl.Header.Get("User-Agent"); ua != "" {
+		route.header.Set("User-Agent", ua)
+	}
+	if proxy.User != nil && !isSocksProxy(proxy.Scheme) {
+		route.auth = t.authFor(proxy)
+	}
+	// -H 指定的头部不会出现在 CONNECT 请求中，--proxy-header 只发送给代理
+	applyProxyHeader(route.header, t.curl.ProxyHeader)
+	return route, nil
+}
+
+// proxyFor 按照 curl 的规则选择代理：--noproxy/no_proxy 优先，其次是 -x，最后是环境变量
+func (t *proxyTransport) proxyFor(target *url.URL) (*url.URL, error) {
+	noProxy := t.curl.NoProxy
+	if !t.curl.noProxySet {
+		noProxy = getenv("no_proxy", "NO_PROXY")
+	}
+	if matchNoProxy(noProxy, target.Hostname()) {
+		return nil, nil
+	}
+
+	proxy := t.proxy
+	if proxy == nil {
+		// -x "" 表示不使用代理，也不读取环境变量
+		if t.curl.proxySet {
+			return nil, nil
+		}
+		raw := proxyFromEnvironment(target.Scheme)
+		if raw == "" {
+			return nil, nil
+		}
+		var err error
+		if proxy, err = parseProxy(raw, "http"); err != nil {
+			return nil, err
+		}
+	}
+
+	// -U/--proxy-user 覆盖代理地址中的用户信息
+	if t.curl.ProxyUser != "" {
+		withUser := *proxy
+		withUser.User = url.UserPassword(t.curl.ProxyUser, t.curl.ProxyPassword)
+		proxy = &withUser
+	}
+	return proxy, nil
+}
+
+// applyProxyHeader 把 --proxy-header 合并到 header，值为空的头部表示去掉该头部
+func applyProxyHeader(header, extra http.Header) {
+	for key, values := range extra {
+		if strings.Join(values, "") != "" {
+			header[key] = append([]string(nil), values...)
+			continue
+		}
+		header.Del(key)
+		if key == "User-Agent" {
+			// 空的 User-Agent 阻止 net/http 加上默认值
+			header[key] = []string{""}
+		}
+	}
+}
+
+// forwardProxy 实现 http.Transport 的 Proxy，只为转发模式返回代理
+// 到 HTTPS 代理的 TLS 由拨号器完成，因此这里总是返回 http:// 地址
+// 代理认证由 proxyTransport 处理，返回的地址不带用户信息，避免 net/http 再加上 Basic 凭据
+func forwardProxy(req *http.Request) (*url.URL, error) {
+	route := proxyFromContext(req.Context())
+	if route == nil || route.tunnel {
+		return nil, nil
+	}
+	return &url.URL{Scheme: "http", Host: route.proxy.Host}, nil
+}
+
+// configureProxy 设置代理选择
+// 需要在 configureHTTPVersion 之后调用，包装在 h2c 等 Transport 的外层
+func (curl *CURL) configureProxy(ses *requests.Session) {
+	transport := sessionTransport(ses)
+	client := sessionClient(ses)
+	if transport == nil || client == nil {
+		return
+	}
+	// Unix 套接字连接不使用代理
+	if curl.UnixSocket != "" {
+		return
+	}
+
+	proxy, err := curl.newProxyTransport(client.Transport)
+	if err != nil {
+		client.Transport = errorTransport{err: err}
+		return
+	}
+	transport.Proxy = forwardProxy
+	client.Transport = proxy
+}
+
+// dialProxy 按照 route 通过代理建立连接
+// 隧道模式下 addr 是目标地址，转发模式下 addr 是 forwardProxy 返回的代理地址
+// CONNECT 收到 407 挑战时重新连接代理，带上 Proxy-Authorization 再次建立隧道
+func (d *dialer) dialProxy(ctx context.Context, route *proxyRoute, network, addr string) (net.Conn, error) {
+	if !route.tunnel {
+		return d.dialProxyHop(ctx, route, network)
+	}
+
+	// 与直接连接一致，--connect-to 改变代理要连接的目标
+	if host, port, err := net.SplitHostPort(addr); err == nil {
+		addr = net.JoinHostPort(d.rewriteConnectTo(host, port))
+	}
+
+	// 最多重试两次：一次应答初始挑战，一次应答 stale nonce
+	for attempt := 0; ; attempt++ {
+		conn, err := d.dialProxyHop(ctx, route, network)
+		if err != nil {
+			return nil, err
+		}
+		header := route.header
+		if route.auth != nil && header.Get("Proxy-Authorization") == "" {
+			if authorization := route.auth.authorization(http.MethodConnect, addr); authorization != "" {
+				route.auth.logScheme(ctx)
+				header = header.Clone()
+				header.Set("Proxy-Authorization", authorization)
+			}
+		}
+		sent := header.Get("Proxy-Authorization") != ""
+
+		tunnel, err := d.tunnel(ctx, conn, route.proxy, header, addr)
+		if err == nil {
+			return tunnel, nil
+		}
+		conn.Close()
+
+		var proxyErr *ProxyError
+		if route.auth == nil || attempt >= 2 || !errors.As(err, &proxyErr) ||
+			proxyErr.StatusCode != http.StatusProxyAuthRequired || !route.auth.challenge(proxyErr.header, sent) {
+			return nil, err
+		}
+	}
+}
+
+// dialProxyHop 建立到代理本身的连接，指定了 --preproxy 时经过 SOCKS 代理，HTTPS 代理在这里完成 TLS 握手
+func (d *dialer) dialProxyHop(ctx context.Context, route *proxyRoute, network string) (net.Conn, error) {
+	var conn net.Conn
+	if route.preProxy != nil {
+		first, err := d.dialHop(ctx, route.preProxy, network)
+		if err != nil {
+			return nil, err
+		}
+		if conn, err = d.tunnel(ctx, first, route.preProxy, nil, route.proxy.Host); err != nil {
+			first.Close()
+			return nil, err
+		}
+	} else {
+		var err error
+		if conn, err = d.dialHop(ctx, route.proxy, network); err != nil {
+			return nil, err
+		}
+	}
+
+	if route.proxy.Scheme != "https" {
+		return conn, nil
+	}
+	config := route.tlsConfig.Clone()
+	config.ServerName = route.proxy.Hostname()
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return tlsConn, nil
+}
+
+// dialHop 连接代理的地址，代理主机名无法解析时返回 ProxyError
+func (d *dialer) dialHop(ctx context.Context, proxy *url.URL, network string) (net.Conn, error) {
+	conn, err := d.dial(ctx, network, proxy.Host)
+	if err != nil {
+		var dnsErr *net.DNSError
+		if errors.As(err, &dnsErr) {
+			return nil, &ProxyError{Proxy: proxy.Host, Err: err}
+		}
+		return nil, err
+	}
+	return d.tune(conn), nil
+}
+
+// tunnel 在已经连接到代理的 conn 上建立到 addr 的隧道，header 是 CONNECT 请求的头部
+// SOCKS4 和 SOCKS5 在本地解析目标主机，SOCKS4a 和 SOCKS5h 把主机名交给代理解析
+func (d *dialer) tunnel(ctx context.Context, conn net.Conn, proxy *url.URL, header http.Header, addr string) (net.Conn, error) {
+	host, port, err := splitPort(addr)
+	if err != nil {
+		return nil, err
+	}
+	v := verboseFromContext(ctx)
+
+	tunnel := conn
+	if isSocksProxy(proxy.Scheme) {
+		ip := net.ParseIP(host)
+		resolved := "remotely"
+		if ip == nil && (proxy.Scheme == "socks4" || proxy.Scheme == "socks5") {
+			if ip, err = d.resolveLocal(ctx, host, strconv.Itoa(port), proxy.Scheme == "socks4"); err != nil {
+				return nil, err
+			}
+			resolved = "locally"
+		}
+		if v != nil {
+			target := addr
+			if ip != nil {
+				target = net.JoinHostPort(ip.String(), strconv.Itoa(port))
+			}
+			v.infof("%s connect to %s (%s resolved)", strings.ToUpper(strings.TrimSuffix(proxy.Scheme, "h")), target, resolved)
+		}
+		err = handshake(ctx, conn, func() error {
+			if strings.HasPrefix(proxy.Scheme, "socks4") {
+				return socks4Connect(conn, proxy, host, ip, port)
+			}
+			return socks5Connect(conn, proxy, host, ip, port)
+		})
+	} else {
+		if v != nil {
+			v.infof("Establish HTTP proxy tunnel to %s", addr)
+		}
+		traced, done := traceProxyConnect(ctx, conn)
+		err = handshake(ctx, conn, func() error {
+			var err error
+			tunnel, err = httpConnect(traced, proxy, addr, header)
+			return err
+		})
+		done()
+	}
+
+	if err != nil {
+		// 超时和取消保持原样，交给调用方按照超时处理
+		var proxyErr *ProxyError
+		if ctx.Err() == nil && !errors.As(err, &proxyErr) {
+			err = &ProxyError{Proxy: proxy.Host, Err: err}
+		}
+		return nil, err
+	}
+	return tunnel, nil
+}
+
+// resolveLocal 为 SOCKS4/SOCKS5 在本地解析目标主机，同样应用 --resolve 和 -4/-6
+func (d *dialer) resolveLocal(ctx context.Context, host, port string, ipv4Only bool) (net.IP, error) {
+	addresses := d.lookupResolve(host, port)
+	if len(addresses) == 0 {
+		var err error
+		if addresses, err = d.lookupHost(ctx, host); err != nil {
+			return nil, err
+		}
+	}
+	for _, addr := range d.filterAddresses(addresses) {
+		if ip := net.ParseIP(addr); ip != nil && (!ipv4Only || ip.To4() != nil) {
+			return ip, nil
+		}
+	}
+	return nil, fmt.Errorf("no usable address for host %s", host)
+}
+
+// handshake 执行代理握手，ctx 结束（例如 --connect-timeout）时中断阻塞的读写
+func handshake(ctx context.Context, conn net.Conn, fn func() error) error {
+	stop := context.AfterFunc(ctx, func() {
+		conn.SetDeadline(time.Unix(1, 0))
+	})
+	err := fn()
+	if !stop() {
+		// 握手被中断，连接不能再使用
+		return ctx.Err()
+	}
+	return err
+}
+
+// httpConnect 发送 CONNECT 请求建立到 addr 的隧道
+func httpConnect(conn net.Conn, proxy *url.URL, addr string, header http.Header) (net.Conn, error) {
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: addr},
+		Host:   addr,
+		Header: header,
+	}
+	if err := req.Write(conn); err != nil {
+		return nil, err
+	}
+
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		resp.Body.Close()
+		return nil, &ProxyError{
+			Proxy:      proxy.Host,
+			StatusCode: resp.StatusCode,
+			Err:        fmt.Errorf("CONNECT tunnel failed, response %d", resp.StatusCode),
+			header:     resp.Header,
+		}
+	}
+	if br.Buffered() > 0 {
+		// 代理在响应之后立即发送了隧道中的数据
+		return &bufferedConn{Conn: conn, r: br}, nil
+	}
+	return conn, nil
+}
+
+// bufferedConn 先读出 bufio.Reader 中已经缓冲的数据
+type bufferedConn struct {
+	net.Conn
+	r *bufio.Reader
+}
+
+// Read 实现 io.Reader
+func (c *bufferedConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
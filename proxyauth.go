@@ -0,0 +1,97 @@
+package gcurl
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+)
+
+// proxyAuthSchemes 返回 --proxy-basic/--proxy-digest/--proxy-anyauth 允许的认证方案，按优先级排列
+func proxyAuthSchemes(mode string) []string {
+	switch mode {
+	case "digest":
+		return []string{"digest"}
+	case "any":
+		// 与 curl 一致，代理同时提供多种方案时选择最安全的
+		return []string{"digest", "basic"}
+	}
+	return []string{"basic"}
+}
+
+// proxyAuth 保存一个 HTTP 代理的认证状态，在同一个 Session 的 CONNECT 和转发请求之间共享
+// Basic 预先发送凭据；Digest 和 --proxy-anyauth 第一次不带凭据，收到 407 挑战后再计算 Proxy-Authorization
+type proxyAuth struct {
+	mode string
+	user *url.Userinfo
+
+	mu     sync.Mutex
+	scheme string          // 当前使用的方案，为空表示还没有收到挑战
+	digest *Authentication // Digest 挑战的状态，nonce count 在请求之间递增
+}
+
+// newProxyAuth 创建代理 proxy 的认证状态
+func newProxyAuth(mode string, user *url.Userinfo) *proxyAuth {
+	password, _ := user.Password()
+	a := &proxyAuth{mode: mode, user: user, digest: NewDigestAuth(user.Username(), password)}
+	if mode != "digest" && mode != "any" {
+		a.scheme = "basic"
+	}
+	return a
+}
+
+// authorization 返回 method uri 请求的 Proxy-Authorization，还不能认证时返回空字符串
+func (a *proxyAuth) authorization(method, uri string) string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	switch a.scheme {
+	case "basic":
+		password, _ := a.user.Password()
+		return "Basic " + base64.StdEncoding.EncodeToString([]byte(a.user.Username()+":"+password))
+	case "digest":
+		return a.digest.digestAuthorization(method, uri, nil)
+	}
+	return ""
+}
+
+// challenge 根据 407 响应的 Proxy-Authenticate 选择认证方案，返回 false 表示无法应答这次挑战
+// sent 表示被拒绝的请求是否已经带了 Proxy-Authorization
+func (a *proxyAuth) challenge(header http.Header, sent bool) bool {
+	challenges := parseAuthChallenges(header.Values("Proxy-Authenticate"))
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, scheme := range proxyAuthSchemes(a.mode) {
+		ch, ok := findAuthChallenge(challenges, scheme)
+		if !ok {
+			continue
+		}
+		// 已经带了凭据仍被拒绝，且不是 Digest 的 nonce 过期，说明凭据错误
+		if sent && a.scheme == scheme && (scheme != "digest" || !strings.EqualFold(ch.Params["stale"], "true")) {
+			return false
+		}
+		if scheme == "digest" && a.digest.applyDigestChallenge(ch) != nil {
+			continue
+		}
+		a.scheme = scheme
+		return true
+	}
+	return false
+}
+
+// logScheme 在 -v 时输出正在使用的代理认证方案
+func (a *proxyAuth) logScheme(ctx context.Context) {
+	v := verboseFromContext(ctx)
+	if v == nil {
+		return
+	}
+	a.mu.Lock()
+	scheme := a.scheme
+	a.mu.Unlock()
+	name := "Basic"
+	if scheme == "digest" {
+		name = "Digest"
+	}
+	v.infof("Proxy auth using %s with user '%s'", name, a.user.Username())
+}
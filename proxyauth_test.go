@@ -0,0 +1,163 @@
+package gcurl
+
+import (
+	"crypto/md5"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// proxyAuthStandIn 要求请求带有正确的 Proxy-Authorization，否则返回 407 挑战
+type proxyAuthStandIn struct {
+	schemes    []string // 407 响应中提供的认证方案，按顺序输出
+	user, pass string
+	nonce      string
+}
+
+// check 校验 Proxy-Authorization，失败时写出 407 响应并返回 false
+func (a *proxyAuthStandIn) check(w http.ResponseWriter, r *http.Request) bool {
+	if value := r.Header.Get("Proxy-Authorization"); value != "" {
+		for _, scheme := range a.schemes {
+			if scheme == "Basic" && value == "Basic "+base64.StdEncoding.EncodeToString([]byte(a.user+":"+a.pass)) {
+				return true
+			}
+			if scheme == "Digest" && a.checkDigest(r.Method, r.RequestURI, value) {
+				return true
+			}
+		}
+	}
+	for _, scheme := range a.schemes {
+		if scheme == "Digest" {
+			w.Header().Add("Proxy-Authenticate", fmt.Sprintf(`Digest realm="proxy", qop="auth", nonce="%s"`, a.nonce))
+		} else {
+			w.Header().Add("Proxy-Authenticate", `Basic realm="proxy"`)
+		}
+	}
+	w.Header().Set("Connection", "close")
+	w.WriteHeader(http.StatusProxyAuthRequired)
+	return false
+}
+
+// checkDigest 按照 MD5 和 qop=auth 校验 Digest 响应
+func (a *proxyAuthStandIn) checkDigest(method, uri, value string) bool {
+	ch, ok := findAuthChallenge(parseAuthChallenges([]string{value}), "digest")
+	if !ok || ch.Params["username"] != a.user || ch.Params["nonce"] != a.nonce || ch.Params["uri"] != uri {
+		return false
+	}
+	h := func(s string) string {
+		sum := md5.Sum([]byte(s))
+		return hex.EncodeToString(sum[:])
+	}
+	ha1 := h(a.user + ":proxy:" + a.pass)
+	ha2 := h(method + ":" + uri)
+	p := ch.Params
+	return p["response"] == h(strings.Join([]string{ha1, a.nonce, p["nc"], p["cnonce"], p["qop"], ha2}, ":"))
+}
+
+// authorizations 返回代理收到的每个请求的 Proxy-Authorization 方案，没有时为空字符串
+func (p *httpProxyStandIn) authorizations() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	schemes := make([]string, 0, len(p.headers))
+	for _, header := range p.headers {
+		scheme, _, _ := strings.Cut(header.Get("Proxy-Authorization"), " ")
+		schemes = append(schemes, scheme)
+	}
+	return schemes
+}
+
+func TestProxyAuthentication(t *testing.T) {
+	_, securePort := proxyOrigins(t)
+	forward := ` http://origin.test/path`
+	tunnel := ` https://origin.test:` + securePort + `/`
+
+	tests := []struct {
+		name    string
+		schemes []string
+		options string
+		target  string
+		want    []string // 代理依次收到的认证方案
+	}{
+		{"Basic is sent preemptively", []string{"Basic"}, "", forward, []string{"Basic"}},
+		{"Explicit basic over CONNECT", []string{"Basic"}, "--proxy-basic", tunnel, []string{"Basic"}},
+		{"Digest forward", []string{"Digest"}, "--proxy-digest", forward, []string{"", "Digest"}},
+		{"Digest CONNECT", []string{"Digest"}, "--proxy-digest", tunnel, []string{"", "Digest"}},
+		{"Anyauth prefers digest", []string{"Basic", "Digest"}, "--proxy-anyauth", tunnel, []string{"", "Digest"}},
+		{"Anyauth falls back to basic", []string{"Basic"}, "--proxy-anyauth", forward, []string{"", "Basic"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			proxy := newHTTPProxyStandIn(t)
+			proxy.auth = &proxyAuthStandIn{schemes: tt.schemes, user: "bob", pass: "s3cret", nonce: "n1"}
+
+			body := executeContent(t, `curl -k `+tt.options+` -x `+proxy.URL+` -U bob:s3cret`+tt.target)
+			wantBody := "origin origin.test:" + securePort
+			if tt.target == forward {
+				wantBody = "via proxy: http://origin.test/path"
+			}
+			if body != wantBody {
+				t.Errorf("body = %q, want %q", body, wantBody)
+			}
+			if got := proxy.authorizations(); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("proxy saw authorization schemes %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProxyAuthenticationRejected(t *testing.T) {
+	_, securePort := proxyOrigins(t)
+	proxy := newHTTPProxyStandIn(t)
+	proxy.auth = &proxyAuthStandIn{schemes: []string{"Digest"}, user: "bob", pass: "s3cret", nonce: "n1"}
+
+	t.Run("CONNECT", func(t *testing.T) {
+		curl, err := Parse(`curl -k --proxy-digest -x ` + proxy.URL + ` -U bob:wrong https://origin.test:` + securePort + `/`)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		_, err = curl.Execute()
+		var proxyErr *ProxyError
+		if !errors.As(err, &proxyErr) || proxyErr.StatusCode != http.StatusProxyAuthRequired {
+			t.Fatalf("expected ProxyError with 407, got %v", err)
+		}
+	})
+
+	t.Run("Forward", func(t *testing.T) {
+		curl, err := Parse(`curl --proxy-digest -x ` + proxy.URL + ` -U bob:wrong http://origin.test/`)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		resp, err := curl.Execute()
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if resp.GetStatusCode() != http.StatusProxyAuthRequired {
+			t.Errorf("status = %d, want 407", resp.GetStatusCode())
+		}
+	})
+}
+
+func TestProxyAuthOptions(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{`curl -x proxy:8080 http://example.com`, ""},
+		{`curl --proxy-basic -x proxy:8080 http://example.com`, "basic"},
+		{`curl --proxy-digest -x proxy:8080 http://example.com`, "digest"},
+		{`curl --proxy-anyauth -x proxy:8080 http://example.com`, "any"},
+	}
+	for _, tt := range tests {
+		curl, err := Parse(tt.cmd)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %v", tt.cmd, err)
+		}
+		if curl.ProxyAuth != tt.want {
+			t.Errorf("Parse(%q).ProxyAuth = %q, want %q", tt.cmd, curl.ProxyAuth, tt.want)
+		}
+	}
+}
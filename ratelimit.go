@@ -0,0 +1,134 @@
+package gcurl
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/474420502/requests"
+)
+
+// rateLimiter 是一个令牌桶，每秒产生 rate 个令牌（字节），桶容量为一秒的流量
+// 桶初始为空，因此传输的平均速度从一开始就不会超过限制
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter 创建每秒 bytesPerSecond 字节的限速器
+func newRateLimiter(bytesPerSecond int64) *rateLimiter {
+	return &rateLimiter{rate: float64(bytesPerSecond), last: time.Now()}
+}
+
+// chunk 返回单次读取允许的最大字节数，避免一次读取远超桶容量
+func (l *rateLimiter) chunk(n int) int {
+	if max := int(l.rate); n > max {
+		if max < 1 {
+			return 1
+		}
+		return max
+	}
+	return n
+}
+
+// take 消耗 n 个令牌，令牌不足时等待，直到补足或 ctx 结束
+func (l *rateLimiter) take(ctx context.Context, n int) error {
+	l.mu.Lock()
+	now := time.Now()
+	l.tokens += now.Sub(l.last).Seconds() * l.rate
+	if l.tokens > l.rate {
+		l.tokens = l.rate
+	}
+	l.last = now
+	l.tokens -= float64(n)
+	deficit := -l.tokens
+	l.mu.Unlock()
+
+	if deficit <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(time.Duration(deficit / l.rate * float64(time.Second)))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// rateLimitedReader 按照限速器的速度读取数据
+type rateLimitedReader struct {
+	ctx     context.Context
+	reader  io.ReadCloser
+	limiter *rateLimiter
+}
+
+// Read 实现 io.Reader
+func (r *rateLimitedReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p[:r.limiter.chunk(len(p))])
+	if n > 0 {
+		if waitErr := r.limiter.take(r.ctx, n); waitErr != nil && err == nil {
+			err = waitErr
+		}
+	}
+	return n, err
+}
+
+// Close 实现 io.Closer
+func (r *rateLimitedReader) Close() error {
+	return r.reader.Close()
+}
+
+// rateLimitTransport 对每个请求的上传和下载分别限速
+type rateLimitTransport struct {
+	base http.RoundTripper
+	rate int64
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+
+	if req.Body != nil && req.Body != http.NoBody {
+		upload := newRateLimiter(t.rate)
+		r := req.Clone(ctx)
+		r.Body = &rateLimitedReader{ctx: ctx, reader: req.Body, limiter: upload}
+		if req.GetBody != nil {
+			r.GetBody = func() (io.ReadCloser, error) {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				return &rateLimitedReader{ctx: ctx, reader: body, limiter: upload}, nil
+			}
+		}
+		req = r
+	}
+
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = &rateLimitedReader{ctx: ctx, reader: resp.Body, limiter: newRateLimiter(t.rate)}
+	return resp, nil
+}
+
+// configureRateLimit 为 --limit-rate 包装 Transport，对请求体和响应体限速
+func (curl *CURL) configureRateLimit(ses *requests.Session) {
+	if curl.LimitRate == "" {
+		return
+	}
+	rate, err := parseSizeValue(curl.LimitRate)
+	if err != nil || rate <= 0 {
+		return
+	}
+	if client := sessionClient(ses); client != nil {
+		client.Transport = &rateLimitTransport{base: client.Transport, rate: rate}
+	}
+}
@@ -0,0 +1,131 @@
+package gcurl
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSizeValue(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int64
+		wantErr bool
+	}{
+		{"1000", 1000, false},
+		{"200K", 200 * 1024, false},
+		{"200k", 200 * 1024, false},
+		{"1.5M", 1536 * 1024, false},
+		{"1G", 1 << 30, false},
+		{"2t", 2 << 40, false},
+		{"0", 0, false},
+		{"", 0, true},
+		{"K", 0, true},
+		{"abc", 0, true},
+		{"-1K", 0, true},
+		{"10X", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseSizeValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSizeValue(%q) error = %v, wantErr %v", tt.value, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parseSizeValue(%q) = %d, want %d", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLimitRateParsing(t *testing.T) {
+	curl, err := Parse(`curl --limit-rate 200K https://example.com`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if curl.LimitRate != "200K" {
+		t.Errorf("LimitRate = %q, want 200K", curl.LimitRate)
+	}
+
+	if _, err := Parse(`curl --limit-rate fast https://example.com`); err == nil {
+		t.Error("Expected error for invalid --limit-rate")
+	}
+}
+
+func TestLimitRateExecution(t *testing.T) {
+	const size = 8 * 1024
+
+	t.Run("Download", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(strings.Repeat("x", size)))
+		}))
+		defer srv.Close()
+
+		curl, err := Parse(`curl --limit-rate 16K ` + srv.URL)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		start := time.Now()
+		resp, err := curl.Execute()
+		if err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if len(resp.Content()) != size {
+			t.Errorf("body length = %d, want %d", len(resp.Content()), size)
+		}
+		// 8K 数据以 16K/s 的速度传输至少需要 0.5 秒
+		if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+			t.Errorf("download not throttled, elapsed %v", elapsed)
+		}
+	})
+
+	t.Run("Upload", func(t *testing.T) {
+		var received int
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			received = len(body)
+		}))
+		defer srv.Close()
+
+		curl, err := Parse(`curl --limit-rate 16K -d ` + strings.Repeat("y", size) + ` ` + srv.URL)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		start := time.Now()
+		if _, err := curl.Execute(); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if received != size {
+			t.Errorf("received = %d, want %d", received, size)
+		}
+		if elapsed := time.Since(start); elapsed < 400*time.Millisecond {
+			t.Errorf("upload not throttled, elapsed %v", elapsed)
+		}
+	})
+
+	t.Run("Unlimited", func(t *testing.T) {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte(strings.Repeat("x", size)))
+		}))
+		defer srv.Close()
+
+		curl, err := Parse(`curl ` + srv.URL)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+
+		start := time.Now()
+		if _, err := curl.Execute(); err != nil {
+			t.Fatalf("Execute failed: %v", err)
+		}
+		if elapsed := time.Since(start); elapsed > 400*time.Millisecond {
+			t.Errorf("unexpected throttling, elapsed %v", elapsed)
+		}
+	})
+}
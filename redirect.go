@@ -0,0 +1,147 @@
+package gcurl
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/474420502/requests"
+)
+
+// defaultMaxRedirs 与 curl 一致，没有指定 --max-redirs 时最多跟随 30 次重定向
+const defaultMaxRedirs = 30
+
+// TooManyRedirectsError 表示重定向次数超过了 --max-redirs 的限制
+// 可以通过 errors.As 从 Execute 返回的错误中取出
+type TooManyRedirectsError struct {
+	Max int // 允许的最大重定向次数
+}
+
+// Error 实现 error
+func (e *TooManyRedirectsError) Error() string {
+	return fmt.Sprintf("maximum (%d) redirects followed", e.Max)
+}
+
+// ExitCode 返回对应的 curl 退出码
+func (e *TooManyRedirectsError) ExitCode() int {
+	return 47
+}
+
+// configureRedirect 设置重定向策略
+// 未指定 -L 时与 curl 一致，不跟随重定向，直接返回 3xx 响应
+func (curl *CURL) configureRedirect(ses *requests.Session) {
+	requests.WithRedirectPolicy(curl.checkRedirect)(ses)
+}
+
+// redirectLimit 返回实际使用的最大重定向次数，-1 表示不限制
+// 没有指定 --max-redirs 时使用 curl 默认的 30 次
+func (curl *CURL) redirectLimit() int {
+	if curl.MaxRedirs < 0 && !curl.maxRedirsSet {
+		return defaultMaxRedirs
+	}
+	return curl.MaxRedirs
+}
+
+// checkRedirect 实现 http.Client 的 CheckRedirect
+// req 是即将发送的重定向请求，via 是之前已经发送的请求，最早的在前
+func (curl *CURL) checkRedirect(req *http.Request, via []*http.Request) error {
+	if !curl.FollowRedirect {
+		return http.ErrUseLastResponse
+	}
+	if maxRedirs := curl.redirectLimit(); maxRedirs >= 0 && len(via) > maxRedirs {
+		return &TooManyRedirectsError{Max: maxRedirs}
+	}
+
+	if v := verboseFromContext(req.Context()); v != nil {
+		v.redirect(req)
+	}
+
+	prev := via[len(via)-1]
+	if req.Response != nil {
+		if err := curl.applyRedirectMethod(req, prev, req.Response.StatusCode); err != nil {
+			return err
+		}
+	}
+
+	// 认证信息和 Cookie 头只发送给原始主机，除非指定了 --location-trusted
+	origin := via[0]
+	if curl.LocationTrusted || sameOrigin(origin, req) {
+		for _, key := range []string{"Authorization", "Cookie"} {
+			if value := origin.Header.Get(key); value != "" {
+				req.Header.Set(key, value)
+			}
+		}
+	} else {
+		req.Header.Del("Authorization")
+		req.Header.Del("Cookie")
+	}
+	return nil
+}
+
+// applyRedirectMethod 按照 curl 的规则决定重定向请求使用的方法
+//   - 301/302: POST 改为 GET（除非指定 --post301/--post302），其他方法保持不变
+//   - 303: 除 HEAD 外都改为 GET（POST 在指定 --post303 时保持不变）
+//   - 307/308: 方法和请求体都保持不变
+func (curl *CURL) applyRedirectMethod(req, prev *http.Request, status int) error {
+	method := prev.Method
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound:
+		if method == http.MethodPost && !(status == http.StatusMovedPermanently && curl.Post301 || status == http.StatusFound && curl.Post302) {
+			method = http.MethodGet
+		}
+	case http.StatusSeeOther:
+		if method != http.MethodHead && !(method == http.MethodPost && curl.Post303) {
+			method = http.MethodGet
+		}
+	default:
+		return nil
+	}
+
+	req.Method = method
+	if method == http.MethodGet || method == http.MethodHead {
+		// 转为 GET 时丢弃请求体及其描述头
+		req.Body = nil
+		req.GetBody = nil
+		req.ContentLength = 0
+		req.Header.Del("Content-Type")
+		return nil
+	}
+
+	// 保持原方法时需要重新发送请求体
+	if prev.GetBody != nil {
+		body, err := prev.GetBody()
+		if err != nil {
+			return err
+		}
+		req.Body = body
+		req.GetBody = prev.GetBody
+		req.ContentLength = prev.ContentLength
+	}
+	return nil
+}
+
+// sameOrigin 判断两个请求的协议、主机和端口是否相同
+func sameOrigin(a, b *http.Request) bool {
+	return a.URL.Scheme == b.URL.Scheme && a.URL.Host == b.URL.Host
+}
+
+// RedirectChain 返回得到该响应所经过的完整重定向链，按时间顺序排列，最后一个是最终响应
+// 中间响应的 Body 已经关闭，只能读取状态码、头部和对应的请求
+func RedirectChain(resp *requests.Response) []*http.Response {
+	if resp == nil {
+		return nil
+	}
+	return redirectChain(resp.GetResponse())
+}
+
+// redirectChain 沿着 Request.Response 回溯得到完整的重定向链
+func redirectChain(resp *http.Response) []*http.Response {
+	var chain []*http.Response
+	for r := resp; r != nil; {
+		chain = append([]*http.Response{r}, chain...)
+		if r.Request == nil {
+			break
+		}
+		r = r.Request.Response
+	}
+	return chain
+}
@@ -1,6 +1,13 @@
 package gcurl
 
 import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
 	"testing"
 )
 
@@ -29,6 +36,12 @@ func TestRedirectHandling(t *testing.T) {
 			expectFollow:    true,
 			expectMaxRedirs: 5,
 		},
+		{
+			name:            "Unlimited max-redirs before location flag",
+			curlCmd:         `curl --max-redirs -1 -L https://httpbin.org/redirect/3`,
+			expectFollow:    true,
+			expectMaxRedirs: -1,
+		},
 		{
 			name:            "Max-redirs without location flag",
 			curlCmd:         `curl --max-redirs 10 https://httpbin.org/redirect/3`,
@@ -55,6 +68,33 @@ func TestRedirectHandling(t *testing.T) {
 	}
 }
 
+func TestRedirectDebugLimit(t *testing.T) {
+	tests := []struct {
+		cmd  string
+		want string
+	}{
+		{`curl -L https://example.com`, "Max Redirects: 30\n"},
+		{`curl -L --max-redirs 5 https://example.com`, "Max Redirects: 5\n"},
+		{`curl -L --max-redirs -1 https://example.com`, "Max Redirects: unlimited\n"},
+	}
+	for _, tt := range tests {
+		curl, err := Parse(tt.cmd)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if got := curl.Debug(); !strings.Contains(got, tt.want) {
+			t.Errorf("%s: debug output missing %q", tt.cmd, tt.want)
+		}
+	}
+
+	// 直接设置 FollowRedirect 时显示实际使用的默认限制
+	curl, _ := Parse(`curl https://example.com`)
+	curl.FollowRedirect = true
+	if got := curl.Debug(); !strings.Contains(got, "Max Redirects: 30\n") {
+		t.Errorf("debug output should show the default limit:\n%s", got)
+	}
+}
+
 func TestRedirectImprovement(t *testing.T) {
 	// 测试新的重定向实现不再使用自定义Header
 	curlCmd := `curl -L https://httpbin.org/redirect/3`
@@ -73,3 +113,224 @@ func TestRedirectImprovement(t *testing.T) {
 		t.Error("FollowRedirect should be true when -L is specified")
 	}
 }
+
+// newRedirectServer 创建测试用服务：/redirect/{n} 连续重定向 n 次，/status/{code} 以指定状态码重定向到 /echo
+func newRedirectServer(t *testing.T, echoURL string) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.HandleFunc("/redirect/", func(w http.ResponseWriter, r *http.Request) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/redirect/"))
+		if n <= 1 {
+			http.Redirect(w, r, "/echo", http.StatusFound)
+			return
+		}
+		http.Redirect(w, r, fmt.Sprintf("/redirect/%d", n-1), http.StatusFound)
+	})
+	mux.HandleFunc("/status/", func(w http.ResponseWriter, r *http.Request) {
+		code, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/status/"))
+		target := "/echo"
+		if echoURL != "" {
+			target = echoURL
+		}
+		http.Redirect(w, r, target, code)
+	})
+	mux.HandleFunc("/echo", echoHandler)
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func echoHandler(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	w.Header().Set("X-Method", r.Method)
+	w.Header().Set("X-Body", string(body))
+	w.Header().Set("X-Authorization", r.Header.Get("Authorization"))
+	w.Header().Set("X-Cookie", r.Header.Get("Cookie"))
+}
+
+func TestRedirectFollowing(t *testing.T) {
+	srv := newRedirectServer(t, "")
+
+	t.Run("Not followed without -L", func(t *testing.T) {
+		curl, err := Parse(`curl ` + srv.URL + `/redirect/1`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := curl.Request().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetStatusCode() != http.StatusFound {
+			t.Errorf("Expected 302, got %d", resp.GetStatusCode())
+		}
+		if chain := RedirectChain(resp); len(chain) != 1 {
+			t.Errorf("Expected chain of 1, got %d", len(chain))
+		}
+	})
+
+	t.Run("Chain exposed", func(t *testing.T) {
+		curl, err := Parse(`curl -L ` + srv.URL + `/redirect/3`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := curl.Request().Execute()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.GetStatusCode() != http.StatusOK {
+			t.Fatalf("Expected 200, got %d", resp.GetStatusCode())
+		}
+
+		chain := RedirectChain(resp)
+		wantPaths := []string{"/redirect/3", "/redirect/2", "/redirect/1", "/echo"}
+		if len(chain) != len(wantPaths) {
+			t.Fatalf("Expected chain of %d, got %d", len(wantPaths), len(chain))
+		}
+		for i, r := range chain {
+			if r.Request.URL.Path != wantPaths[i] {
+				t.Errorf("hop %d: path %s, want %s", i, r.Request.URL.Path, wantPaths[i])
+			}
+			wantStatus := http.StatusFound
+			if i == len(chain)-1 {
+				wantStatus = http.StatusOK
+			}
+			if r.StatusCode != wantStatus {
+				t.Errorf("hop %d: status %d, want %d", i, r.StatusCode, wantStatus)
+			}
+		}
+	})
+
+	t.Run("Max redirs enforced", func(t *testing.T) {
+		curl, err := Parse(`curl -L --max-redirs 2 ` + srv.URL + `/redirect/3`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = curl.Request().Execute()
+		if err == nil || !strings.Contains(err.Error(), "maximum (2) redirects followed") {
+			t.Errorf("Expected max redirects error, got %v", err)
+		}
+
+		var redirErr *TooManyRedirectsError
+		if !errors.As(err, &redirErr) || redirErr.Max != 2 {
+			t.Errorf("Expected TooManyRedirectsError, got %v", err)
+		}
+		if code := curl.exitCode(nil, err); code != 47 {
+			t.Errorf("exit code = %d, want 47", code)
+		}
+
+		curl, _ = Parse(`curl -L --max-redirs 3 ` + srv.URL + `/redirect/3`)
+		if _, err := curl.Request().Execute(); err != nil {
+			t.Errorf("Expected 3 redirects to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("Default limit without -L parsing", func(t *testing.T) {
+		// 直接设置 FollowRedirect 时同样使用 curl 默认的 30 次限制
+		curl, err := Parse(`curl ` + srv.URL + `/redirect/31`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		curl.FollowRedirect = true
+		_, err = curl.Request().Execute()
+		var redirErr *TooManyRedirectsError
+		if !errors.As(err, &redirErr) || redirErr.Max != 30 {
+			t.Errorf("Expected TooManyRedirectsError with limit 30, got %v", err)
+		}
+
+		curl, _ = Parse(`curl ` + srv.URL + `/redirect/30`)
+		curl.FollowRedirect = true
+		if _, err := curl.Request().Execute(); err != nil {
+			t.Errorf("Expected 30 redirects to be allowed, got %v", err)
+		}
+	})
+
+	t.Run("Unlimited redirects", func(t *testing.T) {
+		curl, err := Parse(`curl -L --max-redirs -1 ` + srv.URL + `/redirect/40`)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := curl.Request().Execute(); err != nil {
+			t.Errorf("Expected unlimited redirects, got %v", err)
+		}
+	})
+}
+
+func TestRedirectMethodRules(t *testing.T) {
+	srv := newRedirectServer(t, "")
+
+	tests := []struct {
+		name       string
+		args       string
+		status     int
+		wantMethod string
+		wantBody   string
+	}{
+		{"301 POST becomes GET", `-d a=1`, 301, "GET", ""},
+		{"301 POST kept with --post301", `--post301 -d a=1`, 301, "POST", "a=1"},
+		{"302 POST becomes GET", `-d a=1`, 302, "GET", ""},
+		{"302 POST kept with --post302", `--post302 -d a=1`, 302, "POST", "a=1"},
+		{"302 PUT kept", `-X PUT -d a=1`, 302, "PUT", "a=1"},
+		{"303 POST becomes GET", `-d a=1`, 303, "GET", ""},
+		{"303 POST kept with --post303", `--post303 -d a=1`, 303, "POST", "a=1"},
+		{"303 PUT becomes GET", `-X PUT -d a=1 --post303`, 303, "GET", ""},
+		{"307 POST kept", `-d a=1`, 307, "POST", "a=1"},
+		{"308 POST kept", `-d a=1`, 308, "POST", "a=1"},
+		{"--post302 does not affect 301", `--post302 -d a=1`, 301, "GET", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curl, err := Parse(fmt.Sprintf(`curl -L %s %s/status/%d`, tt.args, srv.URL, tt.status))
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := curl.Request().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := resp.GetHeader()
+			if header.Get("X-Method") != tt.wantMethod {
+				t.Errorf("Expected method %s, got %s", tt.wantMethod, header.Get("X-Method"))
+			}
+			if header.Get("X-Body") != tt.wantBody {
+				t.Errorf("Expected body %q, got %q", tt.wantBody, header.Get("X-Body"))
+			}
+		})
+	}
+}
+
+func TestRedirectCredentialStripping(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(echoHandler))
+	defer other.Close()
+	srv := newRedirectServer(t, other.URL+"/echo")
+
+	tests := []struct {
+		name      string
+		command   string
+		wantCreds bool
+	}{
+		{"Same host keeps credentials", `curl -L -u user:pass -H "Cookie: a=b" ` + srv.URL + `/redirect/1`, true},
+		{"Other host strips credentials", `curl -L -u user:pass -H "Cookie: a=b" ` + srv.URL + `/status/302`, false},
+		{"Location trusted keeps credentials", `curl --location-trusted -u user:pass -H "Cookie: a=b" ` + srv.URL + `/status/302`, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curl, err := Parse(tt.command)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp, err := curl.Request().Execute()
+			if err != nil {
+				t.Fatal(err)
+			}
+			header := resp.GetHeader()
+			hasAuth := header.Get("X-Authorization") != ""
+			hasCookie := strings.Contains(header.Get("X-Cookie"), "a=b")
+			if hasAuth != tt.wantCreds || hasCookie != tt.wantCreds {
+				t.Errorf("Authorization=%q Cookie=%q, want credentials=%v",
+					header.Get("X-Authorization"), header.Get("X-Cookie"), tt.wantCreds)
+			}
+		})
+	}
+}
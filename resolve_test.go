@@ -1,6 +1,10 @@
 package gcurl
 
 import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 )
@@ -242,3 +246,68 @@ func TestResolveVerboseOutput(t *testing.T) {
 	t.Logf("✓ Verbose output generated:")
 	t.Logf("%s", verboseInfo)
 }
+
+// TestResolveExecution 测试 --resolve 在真实请求中生效
+func TestResolveExecution(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("host=" + r.Host))
+	}))
+	defer srv.Close()
+
+	_, port, _ := net.SplitHostPort(srv.Listener.Addr().String())
+
+	testCases := []struct {
+		name    string
+		resolve string
+	}{
+		{name: "Basic mapping", resolve: "api.example.com:" + port + ":127.0.0.1"},
+		{name: "Force form", resolve: "+api.example.com:" + port + ":127.0.0.1"},
+		{name: "Fallback address", resolve: "api.example.com:" + port + ":127.0.0.2,127.0.0.1"},
+		{name: "Wildcard host", resolve: "*:" + port + ":127.0.0.1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			command := fmt.Sprintf(`curl http://api.example.com:%s/ --resolve %s`, port, tc.resolve)
+			curl, err := Parse(command)
+			if err != nil {
+				t.Fatalf("Failed to parse: %v", err)
+			}
+
+			resp, err := curl.Request().Execute()
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+
+			expected := "host=api.example.com:" + port
+			if resp.ContentString() != expected {
+				t.Errorf("Expected body %q, got %q", expected, resp.ContentString())
+			}
+		})
+	}
+}
+
+// TestResolveLookupPriority 测试精确主机映射优先于通配符映射
+func TestResolveLookupPriority(t *testing.T) {
+	curl := New()
+	curl.Resolve = []string{
+		"*:443:10.0.0.1",
+		"example.com:443:10.0.0.2",
+		"example.com:443:10.0.0.3",
+		"ipv6.example.com:443:[::1]",
+	}
+	d := curl.newDialer()
+
+	if addrs := d.lookupResolve("example.com", "443"); len(addrs) != 1 || addrs[0] != "10.0.0.3" {
+		t.Errorf("Expected later entry to win, got %v", addrs)
+	}
+	if addrs := d.lookupResolve("other.com", "443"); len(addrs) != 1 || addrs[0] != "10.0.0.1" {
+		t.Errorf("Expected wildcard entry, got %v", addrs)
+	}
+	if addrs := d.lookupResolve("other.com", "80"); addrs != nil {
+		t.Errorf("Expected no mapping for port 80, got %v", addrs)
+	}
+	if addrs := d.lookupResolve("ipv6.example.com", "443"); len(addrs) != 1 || addrs[0] != "::1" {
+		t.Errorf("Expected bracketed IPv6 address to be unwrapped, got %v", addrs)
+	}
+}
@@ -0,0 +1,100 @@
+package gcurl
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// resumeOffset 返回 -C 对应的续传偏移
+// -C - 时以输出文件当前的大小作为偏移，文件不存在时从头下载
+func (c *CURL) resumeOffset() (int64, error) {
+	if c.ContinueAt >= 0 {
+		return c.ContinueAt, nil
+	}
+
+	path, err := c.determineOutputPath()
+	if err != nil {
+		return 0, err
+	}
+	if path == "" {
+		return 0, fmt.Errorf("-C - requires an output file (-o or -O)")
+	}
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to stat output file for resume: %w", err)
+	}
+	return info.Size(), nil
+}
+
+// resumeOpenFlags 根据续传请求的响应决定输出文件的打开方式
+// 返回 ok 为 false 时表示无需写入文件（416 且本地文件已经完整）
+//   - 206: 校验 Content-Range 起点后追加到文件末尾
+//   - 200: 服务端不支持范围请求，重写整个文件
+//   - 416: 本地文件已经完整时视为成功，否则返回错误
+//   - 其他状态码: 返回错误，保留已经下载的部分
+func (c *CURL) resumeOpenFlags(status int, header http.Header, offset int64) (flags int, ok bool, err error) {
+	flags = os.O_CREATE | os.O_WRONLY | os.O_TRUNC
+	if offset <= 0 {
+		return flags, true, nil
+	}
+
+	contentRange := header.Get("Content-Range")
+	switch status {
+	case http.StatusPartialContent:
+		start, _, valid := parseContentRange(contentRange)
+		if !valid || start != offset {
+			return 0, false, fmt.Errorf("server returned wrong range %q, expected to resume at byte %d", contentRange, offset)
+		}
+		return os.O_CREATE | os.O_WRONLY | os.O_APPEND, true, nil
+	case http.StatusRequestedRangeNotSatisfiable:
+		if _, total, valid := parseContentRange(contentRange); valid && total >= 0 && total != offset {
+			return 0, false, fmt.Errorf("cannot resume at byte %d: remote file is %d bytes", offset, total)
+		}
+		return 0, false, nil
+	case http.StatusOK:
+		return flags, true, nil
+	}
+	return 0, false, fmt.Errorf("cannot resume at byte %d: server returned status %d", offset, status)
+}
+
+// parseContentRange 解析 Content-Range 头，格式为 "bytes start-end/total" 或 "bytes */total"
+// 未知的起点和总长度返回 -1
+func parseContentRange(value string) (start, total int64, ok bool) {
+	spec, found := strings.CutPrefix(strings.TrimSpace(value), "bytes ")
+	if !found {
+		return 0, 0, false
+	}
+	rng, size, found := strings.Cut(spec, "/")
+	if !found {
+		return 0, 0, false
+	}
+
+	total = -1
+	if size != "*" {
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		total = n
+	}
+
+	start = -1
+	if rng != "*" {
+		first, _, found := strings.Cut(rng, "-")
+		if !found {
+			return 0, 0, false
+		}
+		n, err := strconv.ParseInt(first, 10, 64)
+		if err != nil {
+			return 0, 0, false
+		}
+		start = n
+	}
+	return start, total, true
+}
@@ -0,0 +1,169 @@
+package gcurl
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseContentRange(t *testing.T) {
+	tests := []struct {
+		value      string
+		start, end int64
+		ok         bool
+	}{
+		{"bytes 100-199/1000", 100, 1000, true},
+		{"bytes 0-9/*", 0, -1, true},
+		{"bytes */1000", -1, 1000, true},
+		{"items 0-9/10", 0, 0, false},
+		{"bytes abc-9/10", 0, 0, false},
+		{"", 0, 0, false},
+	}
+
+	for _, tt := range tests {
+		start, total, ok := parseContentRange(tt.value)
+		if ok != tt.ok || (ok && (start != tt.start || total != tt.end)) {
+			t.Errorf("parseContentRange(%q) = %d, %d, %v", tt.value, start, total, ok)
+		}
+	}
+}
+
+func TestResumeDownload(t *testing.T) {
+	content := []byte(strings.Repeat("0123456789", 10))
+
+	var lastRange string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastRange = r.Header.Get("Range")
+		if r.URL.Path == "/unavailable" {
+			http.Error(w, "try again later", http.StatusServiceUnavailable)
+			return
+		}
+		if r.URL.Path == "/norange" {
+			// 不支持范围请求的服务端总是返回完整内容
+			w.Write(content)
+			return
+		}
+		http.ServeContent(w, r, "data.bin", time.Time{}, bytes.NewReader(content))
+	}))
+	defer srv.Close()
+
+	tests := []struct {
+		name      string
+		existing  []byte // nil 表示文件不存在
+		args      string
+		path      string
+		wantRange string
+		wantFile  []byte
+		wantErr   bool
+	}{
+		{"Auto offset appends on 206", content[:30], `-C -`, "/", "bytes=30-", content, false},
+		{"Explicit offset", content[:42], `-C 42`, "/", "bytes=42-", content, false},
+		{"Missing file downloads from start", nil, `-C -`, "/", "", content, false},
+		{"200 rewrites the file", []byte("stale data"), `-C -`, "/norange", "bytes=10-", content, false},
+		{"416 on complete file", content, `-C -`, "/", "bytes=100-", content, false},
+		{"416 with mismatched size", append(append([]byte{}, content...), "extra"...), `-C -`, "/", "bytes=105-", nil, true},
+		{"503 keeps the partial file", content[:30], `-C -`, "/unavailable", "bytes=30-", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			output := filepath.Join(t.TempDir(), "data.bin")
+			if tt.existing != nil {
+				if err := os.WriteFile(output, tt.existing, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			curl, err := Parse(`curl ` + tt.args + ` -o ` + output + ` ` + srv.URL + tt.path)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			resp, err := curl.Execute()
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if lastRange != tt.wantRange {
+				t.Errorf("Range = %q, want %q", lastRange, tt.wantRange)
+			}
+
+			err = curl.SaveToFile(resp)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SaveToFile error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				// 出错时不能破坏已经下载的部分
+				if got, _ := os.ReadFile(output); !bytes.Equal(got, tt.existing) {
+					t.Errorf("file = %q, want unchanged %q", got, tt.existing)
+				}
+				return
+			}
+
+			got, err := os.ReadFile(output)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, tt.wantFile) {
+				t.Errorf("file = %q, want %q", got, tt.wantFile)
+			}
+		})
+	}
+}
+
+func TestResumeWrongRange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Range", "bytes 0-9/10")
+		w.WriteHeader(http.StatusPartialContent)
+		w.Write([]byte("0123456789"))
+	}))
+	defer srv.Close()
+
+	output := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(output, []byte("01234"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	curl, err := Parse(`curl -C - -o ` + output + ` ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	resp, err := curl.Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if err := curl.SaveToFile(resp); err == nil {
+		t.Error("Expected error for mismatched Content-Range")
+	}
+	// 出错时不能破坏已经下载的部分
+	if got, _ := os.ReadFile(output); string(got) != "01234" {
+		t.Errorf("file = %q, want unchanged", got)
+	}
+}
+
+func TestResumeServerError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "try again later", http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	output := filepath.Join(t.TempDir(), "data.bin")
+	if err := os.WriteFile(output, []byte("01234"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	curl, err := Parse(`curl -C - -o ` + output + ` ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := curl.Download(); err == nil {
+		t.Error("Expected error when resuming gets 503")
+	}
+	// 错误响应不能覆盖已经下载的部分
+	if got, _ := os.ReadFile(output); string(got) != "01234" {
+		t.Errorf("file = %q, want unchanged", got)
+	}
+}
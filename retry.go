@@ -0,0 +1,200 @@
+package gcurl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/474420502/requests"
+)
+
+const (
+	// retryInitialBackoff 未指定 --retry-delay 时第一次重试前的等待时间
+	retryInitialBackoff = time.Second
+	// retryMaxBackoff 指数退避的上限，与 curl 一致为 10 分钟
+	retryMaxBackoff = 10 * time.Minute
+)
+
+// Execute 使用 CURL 自己创建的 Session 执行请求，并按照 --retry 等选项重试
+func (curl *CURL) Execute() (*requests.Response, error) {
+	return curl.ExecuteWithSession(curl.CreateSession())
+}
+
+// ExecuteWithSession 使用指定的 Session 执行请求，并按照 --retry 等选项重试
+// 每次重试都会重新构建请求，因此请求体会被完整地重新发送
+func (curl *CURL) ExecuteWithSession(ses *requests.Session) (*requests.Response, error) {
+	resp, err := curl.executeWithRetry(ses, func(req *requests.Request) (*requests.Response, error) {
+		// 与 curl 一致，只有指定了 --compressed 才按照 Content-Encoding 解码返回的响应体，否则保持原样
+		keeper := &encodingKeeper{}
+		if curl.Compressed {
+			req = req.WithMiddleware(&contentDecoder{curl: curl})
+		}
+		resp, err := req.WithMiddleware(keeper).Execute()
+		if resp != nil {
+			keeper.restore(resp.GetResponse())
+		}
+		return resp, err
+	}, nil)
+	// -i 把响应头放在返回的响应体之前
+	// 执行本身的错误（例如 -w 输出失败或 --fail）同样返回给调用方
+	if curl.Include && resp != nil {
+		included, ierr := includeHeaders(resp)
+		if err != nil {
+			return included, err
+		}
+		return included, ierr
+	}
+	return resp, err
+}
+
+// executeWithRetry 为每次尝试创建请求并交给 attempt 执行，直到成功、不可重试或者达到 --retry 的限制
+// 每次得到响应后都会把响应头写入 -D 指定的目标，全部结束后按照 -w 输出最后一次传输的信息
+// 所有尝试共用同一个 -D 和 --trace 目标；beforeRetry 不为 nil 时在每次重试创建请求之前调用
+func (curl *CURL) executeWithRetry(ses *requests.Session, attempt func(req *requests.Request) (*requests.Response, error), beforeRetry func()) (*requests.Response, error) {
+	dump, err := curl.openDumpHeader()
+	if err != nil {
+		return nil, err
+	}
+	if dump != nil {
+		defer dump.Close()
+	}
+	trace, traceOut, err := curl.openTrace()
+	if err != nil {
+		return nil, err
+	}
+	if traceOut != nil {
+		defer traceOut.Close()
+	}
+
+	var stats *transferStats
+	resp, err := curl.retry(func() (*requests.Response, error) {
+		if stats != nil && beforeRetry != nil {
+			beforeRetry()
+		}
+		stats = newTransferStats()
+		ctx := httptrace.WithClientTrace(context.Background(), stats.clientTrace())
+		req := curl.CreateRequest(ses).WithMiddleware(&statsMiddleware{stats: stats})
+		// --trace 记录连接上收发的所有数据，与 curl 一致，此时不再输出 -v 的内容
+		// -v 输出执行过程中真实发生的事件
+		if trace != nil {
+			var logger requests.Middleware
+			ctx, logger = trace.withContext(ctx, curl)
+			req = req.WithMiddleware(logger)
+		} else if curl.Verbose {
+			logger := newVerboseLogger(curl, curl.stderr())
+			ctx = logger.withContext(ctx)
+			req = req.WithMiddleware(logger)
+		}
+		resp, err := attempt(req.WithContext(ctx))
+		stats.finish()
+
+		if dump != nil && resp != nil {
+			if _, werr := dump.Write(formatHeaderChain(resp.GetResponse())); werr != nil && err == nil {
+				err = fmt.Errorf("failed to write dump header: %w", werr)
+			}
+		}
+		return resp, err
+	})
+
+	if curl.WriteOutFormat != "" || curl.writeOutStdin {
+		if werr := curl.writeOut(resp, err, stats); werr != nil && err == nil {
+			err = werr
+		}
+	}
+	return resp, err
+}
+
+// retry 反复调用 attempt，按照 --retry、--retry-delay 和 --retry-max-time 等待和重试
+func (curl *CURL) retry(attempt func() (*requests.Response, error)) (*requests.Response, error) {
+	start := time.Now()
+	backoff := retryInitialBackoff
+
+	for n := 0; ; n++ {
+		resp, err := attempt()
+		if n >= curl.Retry || !curl.shouldRetry(resp, err) {
+			return resp, err
+		}
+
+		delay := curl.RetryDelay
+		if delay <= 0 {
+			delay = backoff
+			backoff *= 2
+			if backoff > retryMaxBackoff {
+				backoff = retryMaxBackoff
+			}
+		}
+		// 服务端通过 Retry-After 指定了等待时间时以服务端为准
+		if resp != nil {
+			if retryAfter, ok := parseRetryAfter(resp.GetHeader().Get("Retry-After")); ok {
+				delay = retryAfter
+			}
+		}
+
+		// --retry-max-time 限制了开始重试的时间窗口
+		if curl.RetryMaxTime > 0 && time.Since(start)+delay > curl.RetryMaxTime {
+			return resp, err
+		}
+		time.Sleep(delay)
+	}
+}
+
+// shouldRetry 按照 curl 的规则判断本次结果是否需要重试
+func (curl *CURL) shouldRetry(resp *requests.Response, err error) bool {
+	if err != nil {
+		if curl.RetryAllErrors {
+			return true
+		}
+		if errors.Is(err, syscall.ECONNREFUSED) {
+			return curl.RetryConnRefused
+		}
+		return isTimeoutError(err)
+	}
+	if resp == nil {
+		return false
+	}
+
+	switch resp.GetStatusCode() {
+	case http.StatusRequestTimeout, http.StatusTooManyRequests,
+		http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	// 与 curl 一致，只有指定了 --fail 时 HTTP 错误才算作 --retry-all-errors 中的错误
+	return curl.RetryAllErrors && curl.FailOnError && resp.GetStatusCode() >= 400
+}
+
+// isTimeoutError 判断错误是否为超时
+func isTimeoutError(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr) && netErr.Timeout()
+}
+
+// parseRetryAfter 解析 Retry-After 头，支持秒数和 HTTP 日期两种格式
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		delay := time.Until(date)
+		if delay < 0 {
+			delay = 0
+		}
+		return delay, true
+	}
+	return 0, false
+}
@@ -0,0 +1,222 @@
+package gcurl
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sync"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestRetryOptionParsing(t *testing.T) {
+	curl, err := Parse(`curl --retry 3 --retry-delay 2 --retry-max-time 0.5 --retry-connrefused --retry-all-errors https://example.com`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	if curl.Retry != 3 {
+		t.Errorf("Retry = %d, want 3", curl.Retry)
+	}
+	if curl.RetryDelay != 2*time.Second {
+		t.Errorf("RetryDelay = %v, want 2s", curl.RetryDelay)
+	}
+	if curl.RetryMaxTime != 500*time.Millisecond {
+		t.Errorf("RetryMaxTime = %v, want 500ms", curl.RetryMaxTime)
+	}
+	if !curl.RetryConnRefused || !curl.RetryAllErrors {
+		t.Error("RetryConnRefused and RetryAllErrors should be true")
+	}
+
+	for _, cmd := range []string{
+		`curl --retry -1 https://example.com`,
+		`curl --retry abc https://example.com`,
+		`curl --retry-delay abc https://example.com`,
+	} {
+		if _, err := Parse(cmd); err == nil {
+			t.Errorf("Expected error for %s", cmd)
+		}
+	}
+}
+
+// flakyServer 前 failures 次请求返回 status，之后返回 200，并记录每次收到的请求体
+type flakyServer struct {
+	mu         sync.Mutex
+	failures   int
+	status     int
+	retryAfter string
+	bodies     []string
+}
+
+func (s *flakyServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bodies = append(s.bodies, string(body))
+	if len(s.bodies) <= s.failures {
+		if s.retryAfter != "" {
+			w.Header().Set("Retry-After", s.retryAfter)
+		}
+		w.WriteHeader(s.status)
+		return
+	}
+	w.Write([]byte("ok"))
+}
+
+func TestRetryExecution(t *testing.T) {
+	tests := []struct {
+		name         string
+		args         string
+		failures     int
+		status       int
+		wantAttempts int
+		wantStatus   int
+	}{
+		{"503 retried until success", `--retry 3 --retry-delay 0.01`, 2, 503, 3, 200},
+		{"429 retried", `--retry 1 --retry-delay 0.01`, 1, 429, 2, 200},
+		{"Retries exhausted", `--retry 1 --retry-delay 0.01`, 5, 502, 2, 502},
+		{"No retry by default", ``, 1, 503, 1, 503},
+		{"404 not transient", `--retry 3 --retry-delay 0.01`, 1, 404, 1, 404},
+		{"404 without --fail not retried by --retry-all-errors", `--retry 3 --retry-delay 0.01 --retry-all-errors`, 1, 404, 1, 404},
+		{"404 retried with --retry-all-errors and --fail", `--retry 3 --retry-delay 0.01 --retry-all-errors -f`, 1, 404, 2, 200},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := &flakyServer{failures: tt.failures, status: tt.status}
+			srv := httptest.NewServer(handler)
+			defer srv.Close()
+
+			curl, err := Parse(`curl ` + tt.args + ` -d payload=1 ` + srv.URL)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+
+			resp, err := curl.Execute()
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if resp.GetStatusCode() != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.GetStatusCode(), tt.wantStatus)
+			}
+			if len(handler.bodies) != tt.wantAttempts {
+				t.Errorf("attempts = %d, want %d", len(handler.bodies), tt.wantAttempts)
+			}
+			// 每次重试都必须重新发送完整的请求体
+			for i, body := range handler.bodies {
+				if body != "payload=1" {
+					t.Errorf("attempt %d body = %q, want payload=1", i+1, body)
+				}
+			}
+		})
+	}
+}
+
+func TestRetryAfterHeader(t *testing.T) {
+	handler := &flakyServer{failures: 1, status: 503, retryAfter: "1"}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	curl, err := Parse(`curl --retry 2 --retry-delay 0.01 ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	start := time.Now()
+	resp, err := curl.Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.GetStatusCode() != 200 {
+		t.Errorf("status = %d, want 200", resp.GetStatusCode())
+	}
+	if elapsed := time.Since(start); elapsed < time.Second {
+		t.Errorf("Retry-After not honored, elapsed %v", elapsed)
+	}
+}
+
+func TestRetryMaxTime(t *testing.T) {
+	handler := &flakyServer{failures: 10, status: 503}
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	curl, err := Parse(`curl --retry 5 --retry-delay 0.2 --retry-max-time 0.3 ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	resp, err := curl.Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if resp.GetStatusCode() != 503 {
+		t.Errorf("status = %d, want 503", resp.GetStatusCode())
+	}
+	if len(handler.bodies) != 2 {
+		t.Errorf("attempts = %d, want 2", len(handler.bodies))
+	}
+}
+
+func TestRetryShouldRetryErrors(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}
+	other := errors.New("tls: bad certificate")
+
+	tests := []struct {
+		name  string
+		setup func(c *CURL)
+		err   error
+		want  bool
+	}{
+		{"Timeout", func(c *CURL) {}, context.DeadlineExceeded, true},
+		{"Connection refused", func(c *CURL) {}, refused, false},
+		{"Connection refused with --retry-connrefused", func(c *CURL) { c.RetryConnRefused = true }, refused, true},
+		{"Other error", func(c *CURL) {}, other, false},
+		{"Other error with --retry-all-errors", func(c *CURL) { c.RetryAllErrors = true }, other, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curl := New()
+			tt.setup(curl)
+			if got := curl.shouldRetry(nil, tt.err); got != tt.want {
+				t.Errorf("shouldRetry() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryConnRefusedExecution(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := ln.Addr().String()
+	ln.Close()
+
+	curl, err := Parse(`curl --retry 2 --retry-delay 0.01 --retry-connrefused http://` + addr)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if _, err := curl.Execute(); err == nil || !errors.Is(err, syscall.ECONNREFUSED) {
+		t.Errorf("Expected connection refused error, got %v", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	if d, ok := parseRetryAfter("120"); !ok || d != 120*time.Second {
+		t.Errorf("parseRetryAfter(120) = %v, %v", d, ok)
+	}
+	future := time.Now().Add(30 * time.Second).UTC().Format(http.TimeFormat)
+	if d, ok := parseRetryAfter(future); !ok || d <= 0 || d > 31*time.Second {
+		t.Errorf("parseRetryAfter(date) = %v, %v", d, ok)
+	}
+	if _, ok := parseRetryAfter("soon"); ok {
+		t.Error("parseRetryAfter(soon) should fail")
+	}
+}
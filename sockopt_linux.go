@@ -0,0 +1,16 @@
+//go:build linux
+
+package gcurl
+
+import "syscall"
+
+// tcpFastOpenConnect 是 Linux 的 TCP_FASTOPEN_CONNECT，syscall 包中没有定义
+const tcpFastOpenConnect = 0x1e
+
+// setTCPFastOpen 在 connect 之前开启 TCP Fast Open
+// 内核不支持时忽略错误，与 curl 一致退回到普通的三次握手
+func setTCPFastOpen(c syscall.RawConn) error {
+	return c.Control(func(fd uintptr) {
+		syscall.SetsockoptInt(int(fd), syscall.IPPROTO_TCP, tcpFastOpenConnect, 1)
+	})
+}
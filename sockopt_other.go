@@ -0,0 +1,10 @@
+//go:build !linux
+
+package gcurl
+
+import "syscall"
+
+// setTCPFastOpen 在不支持 TCP_FASTOPEN_CONNECT 的平台上什么都不做
+func setTCPFastOpen(c syscall.RawConn) error {
+	return nil
+}
@@ -0,0 +1,94 @@
+package gcurl
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTCPOptionsParsing(t *testing.T) {
+	tests := []struct {
+		cmd          string
+		wantInterval time.Duration
+		wantReuse    bool
+		wantFastOpen bool
+	}{
+		{`curl https://example.com`, 0, true, false},
+		{`curl --keepalive https://example.com`, 0, true, false},
+		{`curl --no-keepalive https://example.com`, -1, false, false},
+		{`curl --no-keepalive --keepalive-time 30 https://example.com`, 30 * time.Second, false, false},
+		{`curl --tcp-fastopen --tcp-nodelay https://example.com`, 0, true, true},
+	}
+	for _, tt := range tests {
+		curl, err := Parse(tt.cmd)
+		if err != nil {
+			t.Fatalf("Parse(%s) failed: %v", tt.cmd, err)
+		}
+		d := curl.newDialer()
+		if d.base.KeepAlive != tt.wantInterval {
+			t.Errorf("%s: dialer KeepAlive = %v, want %v", tt.cmd, d.base.KeepAlive, tt.wantInterval)
+		}
+		if curl.KeepAlive != tt.wantReuse {
+			t.Errorf("%s: KeepAlive = %v, want %v", tt.cmd, curl.KeepAlive, tt.wantReuse)
+		}
+		if (d.base.Control != nil) != tt.wantFastOpen {
+			t.Errorf("%s: Control set = %v, want %v", tt.cmd, d.base.Control != nil, tt.wantFastOpen)
+		}
+		if !d.noDelay {
+			t.Errorf("%s: TCP_NODELAY should be on by default", tt.cmd)
+		}
+	}
+
+	for _, value := range []string{"0", "-5", "abc"} {
+		if _, err := Parse(`curl --keepalive-time ` + value + ` https://example.com`); err == nil {
+			t.Errorf("expected error for --keepalive-time %s", value)
+		}
+	}
+}
+
+func TestConnectionReuse(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.RemoteAddr))
+	}))
+	defer srv.Close()
+
+	// 在同一个 Session 上执行两次，比较服务器看到的客户端地址
+	twoRequests := func(cmd string) (string, string) {
+		t.Helper()
+		curl, err := Parse(cmd)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		ses := curl.CreateSession()
+		var addrs []string
+		for i := 0; i < 2; i++ {
+			resp, err := curl.ExecuteWithSession(ses)
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			addrs = append(addrs, resp.ContentString())
+		}
+		return addrs[0], addrs[1]
+	}
+
+	if first, second := twoRequests(`curl ` + srv.URL); first != second {
+		t.Errorf("connections should be reused by default: %s != %s", first, second)
+	}
+	if first, second := twoRequests(`curl --keepalive --tcp-fastopen ` + srv.URL); first != second {
+		t.Errorf("--keepalive should reuse the connection: %s != %s", first, second)
+	}
+	if first, second := twoRequests(`curl --no-keepalive ` + srv.URL); first == second {
+		t.Errorf("--no-keepalive should not reuse the connection: both %s", first)
+	}
+
+	// 关闭 TCP_NODELAY 后请求仍然正常
+	curl, err := Parse(`curl ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	curl.TCPNoDelay = false
+	if _, err := curl.Execute(); err != nil {
+		t.Errorf("Execute without TCP_NODELAY failed: %v", err)
+	}
+}
@@ -0,0 +1,187 @@
+package gcurl
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+)
+
+// socks4Replies SOCKS4 服务端拒绝请求时的错误信息
+var socks4Replies = map[byte]string{
+	0x5b: "request rejected or failed",
+	0x5c: "request failed because client is not running identd",
+	0x5d: "request failed because client's identd could not confirm the user ID",
+}
+
+// socks5Replies SOCKS5 服务端拒绝请求时的错误信息（RFC 1928）
+var socks5Replies = map[byte]string{
+	0x01: "general SOCKS server failure",
+	0x02: "connection not allowed by ruleset",
+	0x03: "network unreachable",
+	0x04: "host unreachable",
+	0x05: "connection refused",
+	0x06: "TTL expired",
+	0x07: "command not supported",
+	0x08: "address type not supported",
+}
+
+// socks4Connect 在已经连接到 SOCKS4/4a 代理的 conn 上请求连接 host:port
+// ip 为 nil 时使用 SOCKS4a，由代理解析 host
+func socks4Connect(conn net.Conn, proxy *url.URL, host string, ip net.IP, port int) error {
+	req := []byte{0x04, 0x01, byte(port >> 8), byte(port)}
+	if ip != nil {
+		ip4 := ip.To4()
+		if ip4 == nil {
+			return fmt.Errorf("SOCKS4 does not support IPv6 address %s", ip)
+		}
+		req = append(req, ip4...)
+	} else {
+		// 0.0.0.x 表示目标地址在用户名之后以主机名给出
+		req = append(req, 0, 0, 0, 1)
+	}
+	req = append(req, proxy.User.Username()...)
+	req = append(req, 0)
+	if ip == nil {
+		req = append(req, host...)
+		req = append(req, 0)
+	}
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	var reply [8]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+	if reply[0] != 0x00 {
+		return fmt.Errorf("unexpected SOCKS4 reply version %d", reply[0])
+	}
+	if reply[1] != 0x5a {
+		if msg, ok := socks4Replies[reply[1]]; ok {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("unknown SOCKS4 reply code %#x", reply[1])
+	}
+	return nil
+}
+
+// socks5Connect 在已经连接到 SOCKS5 代理的 conn 上请求连接 host:port
+// ip 为 nil 时把主机名交给代理解析，代理 URL 带有用户名时使用用户名/密码认证（RFC 1929）
+func socks5Connect(conn net.Conn, proxy *url.URL, host string, ip net.IP, port int) error {
+	methods := []byte{0x00}
+	if proxy.User != nil {
+		methods = append(methods, 0x02)
+	}
+	if _, err := conn.Write(append([]byte{0x05, byte(len(methods))}, methods...)); err != nil {
+		return err
+	}
+	var choice [2]byte
+	if _, err := io.ReadFull(conn, choice[:]); err != nil {
+		return err
+	}
+	if choice[0] != 0x05 {
+		return fmt.Errorf("unexpected SOCKS5 reply version %d", choice[0])
+	}
+	switch choice[1] {
+	case 0x00:
+	case 0x02:
+		if err := socks5Authenticate(conn, proxy.User); err != nil {
+			return err
+		}
+	case 0xff:
+		return errors.New("no acceptable SOCKS5 authentication method")
+	default:
+		return fmt.Errorf("unsupported SOCKS5 authentication method %#x", choice[1])
+	}
+
+	req := []byte{0x05, 0x01, 0x00}
+	switch {
+	case ip.To4() != nil:
+		req = append(append(req, 0x01), ip.To4()...)
+	case ip != nil:
+		req = append(append(req, 0x04), ip.To16()...)
+	default:
+		if len(host) > 255 {
+			return fmt.Errorf("host name %s too long for SOCKS5", host)
+		}
+		req = append(append(req, 0x03, byte(len(host))), host...)
+	}
+	req = binary.BigEndian.AppendUint16(req, uint16(port))
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+
+	var reply [4]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+	if reply[0] != 0x05 {
+		return fmt.Errorf("unexpected SOCKS5 reply version %d", reply[0])
+	}
+	if reply[1] != 0x00 {
+		if msg, ok := socks5Replies[reply[1]]; ok {
+			return errors.New(msg)
+		}
+		return fmt.Errorf("unknown SOCKS5 reply code %#x", reply[1])
+	}
+
+	// 跳过代理返回的绑定地址和端口
+	var skip int
+	switch reply[3] {
+	case 0x01:
+		skip = net.IPv4len
+	case 0x04:
+		skip = net.IPv6len
+	case 0x03:
+		var n [1]byte
+		if _, err := io.ReadFull(conn, n[:]); err != nil {
+			return err
+		}
+		skip = int(n[0])
+	default:
+		return fmt.Errorf("unknown SOCKS5 address type %#x", reply[3])
+	}
+	_, err := io.ReadFull(conn, make([]byte, skip+2))
+	return err
+}
+
+// socks5Authenticate 执行 SOCKS5 用户名/密码认证
+func socks5Authenticate(conn net.Conn, user *url.Userinfo) error {
+	name := user.Username()
+	password, _ := user.Password()
+	if len(name) > 255 || len(password) > 255 {
+		return errors.New("SOCKS5 user name or password too long")
+	}
+	req := []byte{0x01, byte(len(name))}
+	req = append(req, name...)
+	req = append(req, byte(len(password)))
+	req = append(req, password...)
+	if _, err := conn.Write(req); err != nil {
+		return err
+	}
+	var reply [2]byte
+	if _, err := io.ReadFull(conn, reply[:]); err != nil {
+		return err
+	}
+	if reply[1] != 0x00 {
+		return errors.New("SOCKS5 authentication failed")
+	}
+	return nil
+}
+
+// splitPort 拆分 host:port，并把端口转换为整数
+func splitPort(addr string) (string, int, error) {
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", 0, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port < 0 || port > 65535 {
+		return "", 0, fmt.Errorf("invalid port in %s", addr)
+	}
+	return host, port, nil
+}
@@ -1,7 +1,12 @@
 package gcurl
 
 import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
 	"strings"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -57,6 +62,16 @@ func TestEnhancedTimeoutSystem(t *testing.T) {
 				cmd:      `curl --max-time 1h "https://example.com"`,
 				expected: 1 * time.Hour,
 			},
+			{
+				name:     "小数秒",
+				cmd:      `curl --max-time 2.5 "https://example.com"`,
+				expected: 2500 * time.Millisecond,
+			},
+			{
+				name:     "短选项",
+				cmd:      `curl -m 0.25 "https://example.com"`,
+				expected: 250 * time.Millisecond,
+			},
 		}
 
 		for _, tt := range tests {
@@ -94,6 +109,16 @@ func TestEnhancedTimeoutSystem(t *testing.T) {
 				cmd:      `curl --connect-timeout 1m "https://example.com"`,
 				expected: 1 * time.Minute,
 			},
+			{
+				name:     "小数秒",
+				cmd:      `curl --connect-timeout 0.5 "https://example.com"`,
+				expected: 500 * time.Millisecond,
+			},
+			{
+				name:     "省略整数部分",
+				cmd:      `curl --connect-timeout .75 "https://example.com"`,
+				expected: 750 * time.Millisecond,
+			},
 		}
 
 		for _, tt := range tests {
@@ -130,6 +155,8 @@ func TestEnhancedTimeoutSystem(t *testing.T) {
 			`curl --max-time invalid "https://example.com"`,
 			`curl --connect-timeout abc "https://example.com"`,
 			`curl --max-time -1 "https://example.com"`,
+			`curl --max-time -0.5 "https://example.com"`,
+			`curl --connect-timeout NaN "https://example.com"`,
 		}
 
 		for _, cmd := range invalidCmds {
@@ -211,3 +238,119 @@ func TestTimeoutTypeSafety(t *testing.T) {
 		}
 	})
 }
+
+// TestConnectTimeoutDoesNotLimitTransfer 验证 --connect-timeout 不会覆盖 --max-time
+func TestConnectTimeoutDoesNotLimitTransfer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(300 * time.Millisecond)
+		w.Write([]byte("done"))
+	}))
+	defer srv.Close()
+
+	curl, err := Parse(`curl --connect-timeout 0.1 --max-time 5 ` + srv.URL)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	resp, err := curl.Request().Execute()
+	if err != nil {
+		t.Fatalf("连接超时不应该限制传输时间: %v", err)
+	}
+	if string(resp.Content()) != "done" {
+		t.Errorf("响应内容错误: %s", resp.Content())
+	}
+}
+
+func TestMaxTimeLimitsTransfer(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer srv.Close()
+
+	curl, err := Parse(`curl --max-time 0.2 ` + srv.URL)
+	if err != nil {
+		t.Fatalf("解析失败: %v", err)
+	}
+
+	start := time.Now()
+	if _, err := curl.Request().Execute(); err == nil {
+		t.Fatal("期望总超时错误")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("总超时没有生效，耗时 %v", elapsed)
+	}
+}
+
+func TestDialerConnectTimeout(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	curl := New()
+	curl.ConnectTimeout = 100 * time.Millisecond
+	d := curl.newDialer()
+	// 在真正发起连接前阻塞，模拟很慢的连接
+	d.base.Control = func(network, address string, c syscall.RawConn) error {
+		time.Sleep(300 * time.Millisecond)
+		return nil
+	}
+
+	_, err = d.DialContext(context.Background(), "tcp", ln.Addr().String())
+	if err == nil || !strings.Contains(err.Error(), "timed out after 100ms") {
+		t.Errorf("期望连接超时错误，得到 %v", err)
+	}
+}
+
+func TestDialerDNSTimeout(t *testing.T) {
+	curl := New()
+	curl.DNSTimeout = 100 * time.Millisecond
+	d := curl.newDialer()
+	// 使用一个永远不响应的 DNS 服务器
+	d.resolver = &net.Resolver{
+		PreferGo: true,
+		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
+			<-ctx.Done()
+			return nil, ctx.Err()
+		},
+	}
+
+	start := time.Now()
+	_, err := d.DialContext(context.Background(), "tcp", "slow-dns.example:80")
+	if err == nil || !strings.Contains(err.Error(), "resolving host slow-dns.example timed out") {
+		t.Errorf("期望DNS超时错误，得到 %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("DNS超时没有生效，耗时 %v", elapsed)
+	}
+}
+
+func TestTLSHandshakeTimeoutConfiguration(t *testing.T) {
+	tests := []struct {
+		name     string
+		setup    func(c *CURL)
+		expected time.Duration
+	}{
+		{"未设置", func(c *CURL) {}, 0},
+		{"使用连接超时", func(c *CURL) { c.ConnectTimeout = 3 * time.Second }, 3 * time.Second},
+		{"单独设置", func(c *CURL) {
+			c.ConnectTimeout = 3 * time.Second
+			c.TLSHandshakeTimeout = time.Second
+		}, time.Second},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			curl := New()
+			tt.setup(curl)
+			transport := sessionTransport(curl.CreateSession())
+			if transport.TLSHandshakeTimeout != tt.expected {
+				t.Errorf("期望TLS握手超时为 %v，得到 %v", tt.expected, transport.TLSHandshakeTimeout)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package gcurl
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/474420502/requests"
+)
+
+// ExitCodePinnedPubKey 与 curl 服务器公钥和 --pinnedpubkey 不匹配时的退出码一致
+const ExitCodePinnedPubKey = 90
+
+// PinnedPubKeyError 表示服务器证书的公钥与 --pinnedpubkey/--proxy-pinnedpubkey 不匹配
+// 可以通过 errors.As 从 Execute 返回的错误中取出
+type PinnedPubKeyError struct {
+	Host string // TLS 握手使用的服务器名称，连接 IP 地址时为空
+	Hash string // 服务器公钥的 sha256//base64 哈希
+}
+
+// Error 实现 error
+func (e *PinnedPubKeyError) Error() string {
+	if e.Host == "" {
+		return fmt.Sprintf("SSL: public key does not match pinned public key (%s)", e.Hash)
+	}
+	return fmt.Sprintf("SSL: public key of %s does not match pinned public key (%s)", e.Host, e.Hash)
+}
+
+// ExitCode 返回对应的 curl 退出码
+func (e *PinnedPubKeyError) ExitCode() int {
+	return ExitCodePinnedPubKey
+}
+
+// hasTLSOptions 判断是否指定了需要自定义 TLS 配置的选项
+func (curl *CURL) hasTLSOptions() bool {
+	return curl.CACert != "" || curl.CAPath != "" || curl.ClientCert != "" || curl.ClientKey != "" || curl.CRLFile != "" ||
+		curl.PinnedPubKey != ""
+}
+
+// buildTLSConfig 根据 --cacert、--capath、--cert/--key、--crlfile 和 --pinnedpubkey 构建 tls.Config
+func (curl *CURL) buildTLSConfig() (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: curl.Insecure}
+
+	// 与 curl 一致，指定了 --cacert/--capath 时只信任这些 CA，不再使用系统证书
+	if curl.CACert != "" || curl.CAPath != "" {
+		pool := x509.NewCertPool()
+		if curl.CACert != "" {
+			if err := appendCertsFromFile(pool, curl.CACert); err != nil {
+				return nil, err
+			}
+		}
+		if curl.CAPath != "" {
+			if err := appendCertsFromDir(pool, curl.CAPath); err != nil {
+				return nil, err
+			}
+		}
+		config.RootCAs = pool
+	}
+
+	if curl.ClientCert != "" {
+		cert, err := loadClientCertificate(curl.ClientCert, curl.ClientKey)
+		if err != nil {
+			return nil, err
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	if curl.CRLFile != "" {
+		crl, err := loadRevocationList(curl.CRLFile)
+		if err != nil {
+			return nil, err
+		}
+		config.VerifyConnection = crl.verifyConnection
+	}
+
+	// 与 curl 一致，-k 时仍然校验固定的公钥
+	if curl.PinnedPubKey != "" {
+		pins, err := loadPinnedPublicKeys(curl.PinnedPubKey)
+		if err != nil {
+			return nil, err
+		}
+		config.VerifyConnection = chainVerifyConnection(config.VerifyConnection, pins.verifyConnection)
+	}
+
+	return config, nil
+}
+
+// buildProxyTLSConfig 根据 --proxy-cacert、--proxy-capath、--proxy-insecure 和 --proxy-pinnedpubkey 构建连接 HTTPS 代理的 tls.Config
+// 与 curl 一致，-k 和 --cacert 只作用于目标服务器，不影响代理
+func (curl *CURL) buildProxyTLSConfig() (*tls.Config, error) {
+	// 到代理的连接只发送 CONNECT 或 HTTP/1 转发请求
+	config := &tls.Config{InsecureSkipVerify: curl.ProxyInsecure, NextProtos: []string{"http/1.1"}}
+	if curl.ProxyCACert != "" || curl.ProxyCAPath != "" {
+		pool := x509.NewCertPool()
+		if curl.ProxyCACert != "" {
+			if err := appendCertsFromFile(pool, curl.ProxyCACert); err != nil {
+				return nil, err
+			}
+		}
+		if curl.ProxyCAPath != "" {
+			if err := appendCertsFromDir(pool, curl.ProxyCAPath); err != nil {
+				return nil, err
+			}
+		}
+		config.RootCAs = pool
+	}
+	if curl.ProxyPinnedPubKey != "" {
+		pins, err := loadPinnedPublicKeys(curl.ProxyPinnedPubKey)
+		if err != nil {
+			return nil, err
+		}
+		config.VerifyConnection = pins.verifyConnection
+	}
+	return config, nil
+}
+
+// configureTLS 将 TLS 配置应用到 Session 上
+// 证书加载失败时不会静默忽略，而是让之后的请求返回该错误
+func (curl *CURL) configureTLS(ses *requests.Session) {
+	if !curl.hasTLSOptions() {
+		return
+	}
+
+	config, err := curl.buildTLSConfig()
+	if err != nil {
+		if client := sessionClient(ses); client != nil {
+			client.Transport = errorTransport{err: err}
+		}
+		return
+	}
+
+	ses.Config().SetTLSConfig(config)
+}
+
+// appendCertsFromFile 从 PEM 文件中加载 CA 证书
+func appendCertsFromFile(pool *x509.CertPool, path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read CA certificate file %s: %w", path, err)
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return fmt.Errorf("no valid certificates found in CA certificate file: %s", path)
+	}
+	return nil
+}
+
+// appendCertsFromDir 加载目录下所有包含 PEM 证书的文件，无法解析的文件会被跳过
+func appendCertsFromDir(pool *x509.CertPool, dir string) error {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return fmt.Errorf("failed to read CA certificate directory %s: %w", dir, err)
+	}
+
+	found := false
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			continue
+		}
+		if pool.AppendCertsFromPEM(data) {
+			found = true
+		}
+	}
+	if !found {
+		return fmt.Errorf("no valid certificates found in CA certificate directory: %s", dir)
+	}
+	return nil
+}
+
+// loadClientCertificate 加载客户端证书，未指定 --key 时私钥从证书文件中读取
+func loadClientCertificate(certPath, keyPath string) (tls.Certificate, error) {
+	if keyPath == "" {
+		keyPath = certPath
+	}
+
+	certPEM, err := os.ReadFile(certPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read client certificate %s: %w", certPath, err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to read client key %s: %w", keyPath, err)
+	}
+
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("failed to load client certificate %s with key %s: %w", certPath, keyPath, err)
+	}
+	return cert, nil
+}
+
+// revocationList 保存 --crlfile 中的 CRL，文件中可以包含多个签发者的 CRL
+type revocationList struct {
+	path  string
+	lists []*x509.RevocationList
+}
+
+// loadRevocationList 加载 PEM 或 DER 格式的 CRL 文件，PEM 文件中可以依次包含多个 CRL
+func loadRevocationList(path string) (*revocationList, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CRL file %s: %w", path, err)
+	}
+
+	var ders [][]byte
+	for rest := data; ; {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type != "X509 CRL" {
+			return nil, fmt.Errorf("unexpected PEM block %q in CRL file: %s", block.Type, path)
+		}
+		ders = append(ders, block.Bytes)
+	}
+	if len(ders) == 0 {
+		ders = [][]byte{data}
+	}
+
+	crl := &revocationList{path: path}
+	for _, der := range ders {
+		list, err := x509.ParseRevocationList(der)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse CRL file %s: %w", path, err)
+		}
+		crl.lists = append(crl.lists, list)
+	}
+	return crl, nil
+}
+
+// isRevoked 判断证书的序列号是否在 list 中
+func isRevoked(list *x509.RevocationList, cert *x509.Certificate) bool {
+	for _, entry := range list.RevokedCertificates {
+		if entry.SerialNumber.Cmp(cert.SerialNumber) == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// verifyConnection 检查对端证书链中是否有证书已被吊销
+// 与 curl 一致，CRL 文件中必须有服务器证书签发者的 CRL，且 CRL 的签名必须能用签发者的证书验证
+func (crl *revocationList) verifyConnection(state tls.ConnectionState) error {
+	chains := state.VerifiedChains
+	if len(chains) == 0 {
+		chains = [][]*x509.Certificate{state.PeerCertificates}
+	}
+
+	for _, chain := range chains {
+		for i, cert := range chain {
+			// 链末尾的信任锚不需要检查
+			if i > 0 && i == len(chain)-1 {
+				break
+			}
+			var issuer *x509.Certificate
+			if i+1 < len(chain) {
+				issuer = chain[i+1]
+			}
+
+			found := false
+			for _, list := range crl.lists {
+				if !bytes.Equal(cert.RawIssuer, list.RawIssuer) {
+					continue
+				}
+				// CRL 必须由证书的签发者签名，否则不能用来判断吊销状态
+				if issuer == nil {
+					return fmt.Errorf("CRL %s signature verification failed: issuer of certificate %q is not available", crl.path, cert.Subject.CommonName)
+				}
+				if err := list.CheckSignatureFrom(issuer); err != nil {
+					return fmt.Errorf("CRL %s signature verification failed: %w", crl.path, err)
+				}
+				found = true
+				if isRevoked(list, cert) {
+					return fmt.Errorf("certificate %q (serial %s) has been revoked by CRL %s", cert.Subject.CommonName, cert.SerialNumber, crl.path)
+				}
+			}
+			if i == 0 && !found {
+				return fmt.Errorf("no CRL for issuer %q of certificate %q in %s", cert.Issuer.CommonName, cert.Subject.CommonName, crl.path)
+			}
+		}
+	}
+	return nil
+}
+
+// pinnedPublicKeys 保存 --pinnedpubkey 允许的公钥，每一项是 SubjectPublicKeyInfo 的 sha256 哈希
+type pinnedPublicKeys [][sha256.Size]byte
+
+// loadPinnedPublicKeys 解析 --pinnedpubkey 的参数
+//   - sha256//base64 格式的哈希，多个哈希用 ";" 分隔
+//   - 否则是 PEM 或 DER 格式的公钥文件
+func loadPinnedPublicKeys(pin string) (pinnedPublicKeys, error) {
+	if strings.HasPrefix(pin, "sha256//") {
+		var pins pinnedPublicKeys
+		for _, item := range strings.Split(pin, ";") {
+			encoded, ok := strings.CutPrefix(strings.TrimSpace(item), "sha256//")
+			if !ok {
+				return nil, fmt.Errorf("invalid pinned public key %q, expected sha256//base64", item)
+			}
+			sum, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil || len(sum) != sha256.Size {
+				return nil, fmt.Errorf("invalid sha256 hash in pinned public key %q", item)
+			}
+			pins = append(pins, [sha256.Size]byte(sum))
+		}
+		return pins, nil
+	}
+
+	data, err := os.ReadFile(pin)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read pinned public key file %s: %w", pin, err)
+	}
+	der := data
+	if block, _ := pem.Decode(data); block != nil {
+		if block.Type != "PUBLIC KEY" {
+			return nil, fmt.Errorf("unexpected PEM block %q in pinned public key file: %s", block.Type, pin)
+		}
+		der = block.Bytes
+	}
+	if _, err := x509.ParsePKIXPublicKey(der); err != nil {
+		return nil, fmt.Errorf("failed to parse pinned public key file %s: %w", pin, err)
+	}
+	return pinnedPublicKeys{sha256.Sum256(der)}, nil
+}
+
+// verifyConnection 检查服务器证书（证书链的第一张）的公钥是否与其中一个固定的公钥一致
+func (pins pinnedPublicKeys) verifyConnection(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		return &PinnedPubKeyError{Host: state.ServerName}
+	}
+	sum := sha256.Sum256(state.PeerCertificates[0].RawSubjectPublicKeyInfo)
+	for _, pin := range pins {
+		if pin == sum {
+			return nil
+		}
+	}
+	return &PinnedPubKeyError{Host: state.ServerName, Hash: "sha256//" + base64.StdEncoding.EncodeToString(sum[:])}
+}
+
+// chainVerifyConnection 依次执行两个 VerifyConnection 检查，first 可以为 nil
+func chainVerifyConnection(first, next func(tls.ConnectionState) error) func(tls.ConnectionState) error {
+	if first == nil {
+		return next
+	}
+	return func(state tls.ConnectionState) error {
+		if err := first(state); err != nil {
+			return err
+		}
+		return next(state)
+	}
+}
@@ -1,8 +1,25 @@
 package gcurl
 
 import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 )
 
 // TLS/SSL相关 handleCACert/handleClientCert/handleClientKey/handleInsecure
@@ -49,3 +66,417 @@ func TestHandleInsecure(t *testing.T) {
 		t.Error("Insecure not parsed correctly")
 	}
 }
+
+// testPKI 是测试用的证书体系：一个 CA 签发服务端证书和客户端证书
+type testPKI struct {
+	dir       string
+	caCert    *x509.Certificate
+	caKey     *ecdsa.PrivateKey
+	caFile    string
+	server    tls.Certificate
+	serverSN  *big.Int
+	clientCrt string
+	clientKey string
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+}
+
+func newTestPKI(t *testing.T) *testPKI {
+	t.Helper()
+	p := &testPKI{dir: t.TempDir()}
+
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "gcurl test CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	p.caCert, _ = x509.ParseCertificate(caDER)
+	p.caKey = caKey
+	p.caFile = filepath.Join(p.dir, "ca.pem")
+	writePEM(t, p.caFile, "CERTIFICATE", caDER)
+
+	issue := func(serial int64, cn string, usage x509.ExtKeyUsage) ([]byte, *ecdsa.PrivateKey) {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: cn},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+			IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, p.caCert, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatalf("create %s: %v", cn, err)
+		}
+		return der, key
+	}
+
+	serverDER, serverKey := issue(2, "server", x509.ExtKeyUsageServerAuth)
+	p.serverSN = big.NewInt(2)
+	p.server = tls.Certificate{Certificate: [][]byte{serverDER}, PrivateKey: serverKey}
+
+	clientDER, clientKey := issue(3, "client", x509.ExtKeyUsageClientAuth)
+	keyDER, _ := x509.MarshalECPrivateKey(clientKey)
+	p.clientCrt = filepath.Join(p.dir, "client.pem")
+	p.clientKey = filepath.Join(p.dir, "client.key")
+	writePEM(t, p.clientCrt, "CERTIFICATE", clientDER)
+	writePEM(t, p.clientKey, "EC PRIVATE KEY", keyDER)
+
+	return p
+}
+
+// writeCRL 生成吊销了指定序列号的 CRL 文件
+func (p *testPKI) writeCRL(t *testing.T, serials ...*big.Int) string {
+	t.Helper()
+	var revoked []pkix.RevokedCertificate
+	for _, sn := range serials {
+		revoked = append(revoked, pkix.RevokedCertificate{SerialNumber: sn, RevocationTime: time.Now()})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:              big.NewInt(1),
+		ThisUpdate:          time.Now().Add(-time.Hour),
+		NextUpdate:          time.Now().Add(time.Hour),
+		RevokedCertificates: revoked,
+	}, p.caCert, p.caKey)
+	if err != nil {
+		t.Fatalf("create CRL: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "ca.crl")
+	writePEM(t, path, "X509 CRL", der)
+	return path
+}
+
+// startServer 启动使用测试证书的 HTTPS 服务，requireClient 为 true 时要求客户端证书
+func (p *testPKI) startServer(t *testing.T, requireClient bool) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.TLS.PeerCertificates) > 0 {
+			w.Header().Set("X-Client-CN", r.TLS.PeerCertificates[0].Subject.CommonName)
+		}
+		w.Write([]byte("ok"))
+	}))
+	srv.TLS = &tls.Config{Certificates: []tls.Certificate{p.server}}
+	if requireClient {
+		pool := x509.NewCertPool()
+		pool.AddCert(p.caCert)
+		srv.TLS.ClientCAs = pool
+		srv.TLS.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTLSCustomRoots(t *testing.T) {
+	p := newTestPKI(t)
+	srv := p.startServer(t, false)
+
+	caDir := t.TempDir()
+	os.WriteFile(filepath.Join(caDir, "README"), []byte("not a certificate"), 0600)
+	caData, _ := os.ReadFile(p.caFile)
+	os.WriteFile(filepath.Join(caDir, "ca.pem"), caData, 0600)
+
+	tests := []struct {
+		name    string
+		command string
+		wantErr bool
+	}{
+		{"cacert", `curl --cacert ` + p.caFile + ` ` + srv.URL, false},
+		{"capath", `curl --capath ` + caDir + ` ` + srv.URL, false},
+		{"system roots", `curl ` + srv.URL, true},
+		{"capath without certs", `curl --capath ` + t.TempDir() + ` ` + srv.URL, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(tt.command)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			resp, err := c.Request().Execute()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("Expected error, got status %d", resp.GetStatusCode())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if string(resp.Content()) != "ok" {
+				t.Errorf("Unexpected body: %s", resp.Content())
+			}
+		})
+	}
+}
+
+func TestTLSClientCertificate(t *testing.T) {
+	p := newTestPKI(t)
+	srv := p.startServer(t, true)
+
+	// 证书和私钥放在同一个文件中
+	combined := filepath.Join(t.TempDir(), "combined.pem")
+	certData, _ := os.ReadFile(p.clientCrt)
+	keyData, _ := os.ReadFile(p.clientKey)
+	os.WriteFile(combined, append(certData, keyData...), 0600)
+
+	tests := []struct {
+		name    string
+		command string
+	}{
+		{"cert and key", `curl --cacert ` + p.caFile + ` --cert ` + p.clientCrt + ` --key ` + p.clientKey + ` ` + srv.URL},
+		{"combined file", `curl --cacert ` + p.caFile + ` --cert ` + combined + ` ` + srv.URL},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(tt.command)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			resp, err := c.Request().Execute()
+			if err != nil {
+				t.Fatalf("Execute failed: %v", err)
+			}
+			if cn := resp.GetHeader().Get("X-Client-CN"); cn != "client" {
+				t.Errorf("Expected client CN 'client', got %q", cn)
+			}
+		})
+	}
+
+	t.Run("without client cert", func(t *testing.T) {
+		c, err := Parse(`curl --cacert ` + p.caFile + ` ` + srv.URL)
+		if err != nil {
+			t.Fatalf("Parse failed: %v", err)
+		}
+		if _, err := c.Request().Execute(); err == nil {
+			t.Error("Expected handshake error without client certificate")
+		}
+	})
+}
+
+func TestTLSClientKeyMismatch(t *testing.T) {
+	p := newTestPKI(t)
+	other := newTestPKI(t)
+
+	c, err := Parse(`curl --cert ` + p.clientCrt + ` --key ` + other.clientKey + ` https://127.0.0.1:1`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	_, err = c.buildTLSConfig()
+	if err == nil || !strings.Contains(err.Error(), "private key does not match public key") {
+		t.Fatalf("Expected key mismatch error, got %v", err)
+	}
+
+	// 配置错误在执行请求时返回，而不是被静默忽略
+	if _, err := c.Request().Execute(); err == nil || !strings.Contains(err.Error(), "does not match") {
+		t.Errorf("Expected key mismatch error from Execute, got %v", err)
+	}
+}
+
+// otherIssuerCRL 生成由另一个名称的 CA 签发的 CRL
+func otherIssuerCRL(t *testing.T) []byte {
+	t.Helper()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "other CA"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create CA: %v", err)
+	}
+	ca, _ := x509.ParseCertificate(der)
+	crl, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-time.Hour),
+		NextUpdate: time.Now().Add(time.Hour),
+	}, ca, key)
+	if err != nil {
+		t.Fatalf("create CRL: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: crl})
+}
+
+func TestTLSRevocationList(t *testing.T) {
+	p := newTestPKI(t)
+	srv := p.startServer(t, false)
+
+	// 同名的 CA 使用另一把私钥签发的 CRL
+	wrongKey := newTestPKI(t).writeCRL(t, big.NewInt(99))
+
+	otherIssuer := filepath.Join(t.TempDir(), "other.crl")
+	os.WriteFile(otherIssuer, otherIssuerCRL(t), 0600)
+
+	// 多个签发者的 CRL 放在同一个文件中
+	revoked, _ := os.ReadFile(p.writeCRL(t, p.serverSN))
+	bundle := filepath.Join(t.TempDir(), "bundle.crl")
+	os.WriteFile(bundle, append(otherIssuerCRL(t), revoked...), 0600)
+
+	tests := []struct {
+		name    string
+		crl     string
+		wantErr string // 为空表示请求成功
+	}{
+		{"server revoked", p.writeCRL(t, p.serverSN), "revoked"},
+		{"other serial revoked", p.writeCRL(t, big.NewInt(99)), ""},
+		{"CRL signed by the wrong key", wrongKey, "signature verification failed"},
+		{"No CRL for the issuer", otherIssuer, "no CRL for issuer"},
+		{"Revoked in bundle", bundle, "revoked"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(`curl --cacert ` + p.caFile + ` --crlfile ` + tt.crl + ` ` + srv.URL)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			_, err = c.Request().Execute()
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("Execute failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestHandleCAPathAndCRLFile(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "list.crl")
+	os.WriteFile(file, []byte{}, 0600)
+
+	c, err := Parse(`curl --capath ` + dir + ` --crlfile ` + file + ` https://example.com`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if c.CAPath != dir || c.CRLFile != file {
+		t.Errorf("Unexpected CAPath=%s CRLFile=%s", c.CAPath, c.CRLFile)
+	}
+
+	if _, err := Parse(`curl --capath ` + file + ` https://example.com`); err == nil {
+		t.Error("Expected error when --capath is not a directory")
+	}
+	if _, err := Parse(`curl --crlfile /nonexistent/list.crl https://example.com`); err == nil {
+		t.Error("Expected error for missing CRL file")
+	}
+}
+
+// pinnedHash 返回证书公钥的 sha256//base64 哈希
+func pinnedHash(t *testing.T, der []byte) string {
+	t.Helper()
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return "sha256//" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestTLSPinnedPublicKey(t *testing.T) {
+	p := newTestPKI(t)
+	srv := p.startServer(t, false)
+	hash := pinnedHash(t, p.server.Certificate[0])
+	other := pinnedHash(t, p.caCert.Raw)
+
+	serverCert, _ := x509.ParseCertificate(p.server.Certificate[0])
+	pemFile := filepath.Join(t.TempDir(), "server.pub.pem")
+	writePEM(t, pemFile, "PUBLIC KEY", serverCert.RawSubjectPublicKeyInfo)
+	derFile := filepath.Join(t.TempDir(), "server.pub.der")
+	os.WriteFile(derFile, serverCert.RawSubjectPublicKeyInfo, 0600)
+
+	tests := []struct {
+		name     string
+		options  string
+		wantCode int // 0 表示请求成功
+	}{
+		{"hash", `--cacert ` + p.caFile + ` --pinnedpubkey ` + hash, 0},
+		{"one of several hashes", `--cacert ` + p.caFile + ` --pinnedpubkey "` + other + `;` + hash + `"`, 0},
+		{"PEM file", `--cacert ` + p.caFile + ` --pinnedpubkey ` + pemFile, 0},
+		{"DER file", `--cacert ` + p.caFile + ` --pinnedpubkey ` + derFile, 0},
+		{"mismatch", `--cacert ` + p.caFile + ` --pinnedpubkey ` + other, ExitCodePinnedPubKey},
+		{"mismatch with -k", `-k --pinnedpubkey ` + other, ExitCodePinnedPubKey},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := Parse(`curl ` + tt.options + ` ` + srv.URL)
+			if err != nil {
+				t.Fatalf("Parse failed: %v", err)
+			}
+			_, err = c.Execute()
+			if tt.wantCode == 0 {
+				if err != nil {
+					t.Fatalf("Execute failed: %v", err)
+				}
+				return
+			}
+			var pinErr *PinnedPubKeyError
+			if !errors.As(err, &pinErr) || pinErr.Hash != hash {
+				t.Fatalf("expected PinnedPubKeyError with hash %s, got %v", hash, err)
+			}
+			if code := c.exitCode(nil, err); code != tt.wantCode {
+				t.Errorf("exit code = %d, want %d", code, tt.wantCode)
+			}
+		})
+	}
+
+	for _, pin := range []string{"sha256//not-base64!", "sha256//" + base64.StdEncoding.EncodeToString([]byte("short")), p.caFile} {
+		if _, err := loadPinnedPublicKeys(pin); err == nil {
+			t.Errorf("loadPinnedPublicKeys(%q) should fail", pin)
+		}
+	}
+}
+
+func TestProxyPinnedPublicKey(t *testing.T) {
+	proxy := newHTTPSProxyStandIn(t)
+	hash := pinnedHash(t, proxy.Certificate().Raw)
+	other := pinnedHash(t, newTestPKI(t).caCert.Raw)
+
+	body := executeContent(t, `curl --proxy-insecure --proxy-pinnedpubkey `+hash+` -x `+proxy.URL+` http://origin.test/`)
+	if body != "via proxy: http://origin.test/" {
+		t.Errorf("body = %q", body)
+	}
+
+	// --pinnedpubkey 只作用于目标服务器
+	body = executeContent(t, `curl --proxy-insecure --pinnedpubkey `+other+` -x `+proxy.URL+` http://origin.test/`)
+	if body != "via proxy: http://origin.test/" {
+		t.Errorf("body = %q", body)
+	}
+
+	c, err := Parse(`curl --proxy-insecure --proxy-pinnedpubkey ` + other + ` -x ` + proxy.URL + ` http://origin.test/`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	_, err = c.Execute()
+	if code := c.exitCode(nil, err); code != ExitCodePinnedPubKey {
+		t.Errorf("exit code = %d (%v), want %d", code, err, ExitCodePinnedPubKey)
+	}
+}
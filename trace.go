@@ -0,0 +1,472 @@
+package gcurl
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/474420502/requests"
+)
+
+// traceContextKey 是请求 context 中 tracer 的键，拨号时通过它把连接接入追踪
+type traceContextKey struct{}
+
+// traceFromContext 返回 ctx 中的 tracer，没有时返回 nil
+func traceFromContext(ctx context.Context) *tracer {
+	t, _ := ctx.Value(traceContextKey{}).(*tracer)
+	return t
+}
+
+// tracer 按照 curl --trace/--trace-ascii 的格式记录连接上收发的所有数据
+//   - "== Info:" 连接、DNS、TLS 等信息
+//   - "=> Send header" / "=> Send data" 发出的请求头和请求体
+//   - "<= Recv header" / "<= Recv data" 收到的响应头和响应体
+//
+// 数据在 TLS 之上记录，因此 HTTPS 连接记录的是解密后的内容
+type tracer struct {
+	mu     sync.Mutex
+	w      io.Writer
+	ascii  bool // --trace-ascii 只输出文本
+	times  bool // --trace-time 每行前加时间戳
+	ids    bool // --trace-ids 每行前加传输和连接编号
+	conns  int  // 已经建立的连接数，同时是下一个连接的编号
+	lastID int  // 最近使用的连接编号，-1 表示还没有连接
+}
+
+// openTrace 打开 --trace/--trace-ascii 指定的目标，未启用时返回 nil
+// 与 curl 一致，"-" 表示标准输出，每次执行都会重新创建文件
+func (c *CURL) openTrace() (*tracer, io.Closer, error) {
+	if !c.Trace {
+		return nil, nil, nil
+	}
+	var out io.WriteCloser
+	switch c.TraceFile {
+	case "", "-":
+		out = nopWriteCloser{c.stdout()}
+	default:
+		file, err := os.Create(c.TraceFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to create trace file: %w", err)
+		}
+		out = file
+	}
+	t := &tracer{w: out, ascii: c.TraceASCII, times: c.TraceTime, ids: c.TraceIDs, lastID: -1}
+	return t, out, nil
+}
+
+// withContext 在 ctx 上挂载 tracer，并通过一个只输出信息行的 verboseLogger 记录连接过程
+func (t *tracer) withContext(ctx context.Context, curl *CURL) (context.Context, requests.Middleware) {
+	ctx = context.WithValue(ctx, traceContextKey{}, t)
+	logger := newVerboseLogger(curl, io.Discard)
+	logger.info = t.info
+	return logger.withContext(ctx), logger
+}
+
+// prefix 返回 --trace-time 和 --trace-ids 要求的行前缀
+func (t *tracer) prefix(connID int) string {
+	var b strings.Builder
+	if t.times {
+		now := time.Now()
+		fmt.Fprintf(&b, "%s.%06d ", now.Format("15:04:05"), now.Nanosecond()/1000)
+	}
+	if t.ids {
+		// 命令行只有一个传输，编号总是 0
+		if connID < 0 {
+			b.WriteString("[0-x] ")
+		} else {
+			fmt.Fprintf(&b, "[0-%d] ", connID)
+		}
+	}
+	return b.String()
+}
+
+// info 输出一行 "== Info:" 信息
+func (t *tracer) info(msg string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	fmt.Fprintf(t.w, "%s== Info: %s\n", t.prefix(t.lastID), msg)
+}
+
+// newConn 为新建立的连接分配编号
+func (t *tracer) newConn() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	id := t.conns
+	t.conns++
+	t.lastID = id
+	return id
+}
+
+// dump 输出一段数据，格式与 curl 的 --trace（十六进制加文本）或 --trace-ascii（只有文本）一致
+func (t *tracer) dump(connID int, text string, data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.lastID = connID
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s%s, %d bytes (0x%x)\n", t.prefix(connID), text, len(data), len(data))
+	width := 0x10
+	if t.ascii {
+		width = 0x40
+	}
+	for i := 0; i < len(data); i += width {
+		fmt.Fprintf(&b, "%04x: ", i)
+		if !t.ascii {
+			for c := 0; c < width; c++ {
+				if i+c < len(data) {
+					fmt.Fprintf(&b, "%02x ", data[i+c])
+				} else {
+					b.WriteString("   ")
+				}
+			}
+		}
+		for c := 0; c < width && i+c < len(data); c++ {
+			// --trace-ascii 遇到 CRLF 时换行，下一行从 CRLF 之后开始
+			if t.ascii && i+c+1 < len(data) && data[i+c] == '\r' && data[i+c+1] == '\n' {
+				i += c + 2 - width
+				break
+			}
+			ch := data[i+c]
+			if ch < 0x20 || ch >= 0x7f {
+				ch = '.'
+			}
+			b.WriteByte(ch)
+			// 避免 CRLF 正好在行宽处时多输出一个空行
+			if t.ascii && i+c+2 < len(data) && data[i+c+1] == '\r' && data[i+c+2] == '\n' {
+				i += c + 3 - width
+				break
+			}
+		}
+		b.WriteByte('\n')
+	}
+	t.w.Write(b.Bytes())
+}
+
+// traceConn 记录连接上写出和读到的数据
+// HTTP/1.x 的数据按照报文边界区分头部和正文，其他协议（例如 h2c）的数据都记录为 data
+type traceConn struct {
+	net.Conn
+	tracer *tracer
+	id     int
+	out    *traceStream
+	in     *traceStream
+
+	mu      sync.Mutex
+	methods []string // 已发出、还没有收到响应的请求方法
+
+	silent bool // 代理隧道建立之后不再记录，隧道中的数据由外层的 traceConn 记录
+}
+
+// newTraceConn 把 conn 接入 t 的追踪
+// 经过代理隧道的连接沿用记录 CONNECT 时分配的连接编号
+func newTraceConn(t *tracer, conn net.Conn) *traceConn {
+	id, ok := tunnelTraceID(conn)
+	if !ok {
+		id = t.newConn()
+	}
+	c := &traceConn{Conn: conn, tracer: t, id: id}
+	c.out = &traceStream{conn: c}
+	c.in = &traceStream{conn: c, incoming: true}
+	return c
+}
+
+// Write 记录并写出数据
+func (c *traceConn) Write(p []byte) (int, error) {
+	// 先记录再写出，保证收到响应时对应的请求方法已经入队
+	if !c.silent {
+		c.out.feed(p)
+	}
+	return c.Conn.Write(p)
+}
+
+// Read 读取并记录数据
+func (c *traceConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 && !c.silent {
+		c.in.feed(p[:n])
+	}
+	return n, err
+}
+
+// traceProxyConnect 在启用 --trace 时记录 conn 上发给 HTTP 代理的 CONNECT 请求和代理的响应
+// 隧道建立后调用返回的 done 停止记录，之后隧道中的数据由 configureTrace 接入的连接记录
+func traceProxyConnect(ctx context.Context, conn net.Conn) (traced net.Conn, done func()) {
+	t := traceFromContext(ctx)
+	if t == nil {
+		return conn, func() {}
+	}
+	c := newTraceConn(t, conn)
+	return c, func() { c.silent = true }
+}
+
+// tunnelTraceID 返回 conn 所在的代理隧道在记录 CONNECT 时分配的连接编号
+func tunnelTraceID(conn net.Conn) (int, bool) {
+	for {
+		switch c := conn.(type) {
+		case *traceConn:
+			return c.id, true
+		case *tls.Conn:
+			conn = c.NetConn()
+		case *bufferedConn:
+			conn = c.Conn
+		default:
+			return 0, false
+		}
+	}
+}
+
+// pushMethod 记录发出的请求方法
+func (c *traceConn) pushMethod(method string) {
+	c.mu.Lock()
+	c.methods = append(c.methods, method)
+	c.mu.Unlock()
+}
+
+// popMethod 取出与当前响应对应的请求方法
+func (c *traceConn) popMethod() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.methods) == 0 {
+		return ""
+	}
+	method := c.methods[0]
+	c.methods = c.methods[1:]
+	return method
+}
+
+// traceStream 的解析状态
+const (
+	streamHeader       = iota // 等待完整的头部
+	streamBody                // Content-Length 指定长度的正文
+	streamChunkSize           // 分块编码的长度行
+	streamChunkData           // 分块编码的数据和结尾的 CRLF
+	streamChunkTrailer        // 分块编码结束后的 trailer
+	streamRaw                 // 无法区分边界（直到连接关闭的正文、h2c、隧道等），全部记录为 data
+)
+
+// maxTraceHeader 头部的最大缓冲长度，超过后不再按 HTTP/1.x 解析
+const maxTraceHeader = 1 << 20
+
+// traceStream 按照 HTTP/1.x 的报文边界把一个方向上的数据分成头部和正文
+type traceStream struct {
+	conn      *traceConn
+	incoming  bool
+	state     int
+	pending   []byte // 还没有凑成完整头部或者分块长度行的数据
+	remaining int64  // 当前正文或分块剩余的字节数
+}
+
+// feed 处理新读到或写出的一段数据
+func (s *traceStream) feed(p []byte) {
+	for len(p) > 0 {
+		switch s.state {
+		case streamHeader:
+			if len(s.pending) == 0 && !s.looksLikeHTTP(p) {
+				s.state = streamRaw
+				continue
+			}
+			s.pending = append(s.pending, p...)
+			end := bytes.Index(s.pending, []byte("\r\n\r\n"))
+			if end < 0 {
+				if len(s.pending) > maxTraceHeader {
+					s.state = streamRaw
+					p, s.pending = s.pending, nil
+					continue
+				}
+				return
+			}
+			end += len("\r\n\r\n")
+			head := s.pending[:end]
+			p = append([]byte(nil), s.pending[end:]...)
+			s.pending = nil
+			s.header(head)
+			s.state = s.next(head)
+		case streamBody, streamChunkData:
+			n := int64(len(p))
+			if n > s.remaining {
+				n = s.remaining
+			}
+			s.data(p[:n])
+			p = p[n:]
+			s.remaining -= n
+			if s.remaining == 0 {
+				if s.state == streamBody {
+					s.state = streamHeader
+				} else {
+					s.state = streamChunkSize
+				}
+			}
+		case streamChunkSize, streamChunkTrailer:
+			i := bytes.IndexByte(p, '\n')
+			if i < 0 {
+				s.pending = append(s.pending, p...)
+				s.data(p)
+				return
+			}
+			line := append(s.pending, p[:i+1]...)
+			s.pending = nil
+			s.data(p[:i+1])
+			p = p[i+1:]
+			s.chunkLine(strings.TrimSpace(string(line)))
+		case streamRaw:
+			s.data(p)
+			return
+		}
+	}
+}
+
+// looksLikeHTTP 判断数据是否以 HTTP/1.x 的请求行或状态行开头
+func (s *traceStream) looksLikeHTTP(p []byte) bool {
+	if s.incoming {
+		return p[0] == 'H'
+	}
+	// HTTP/2 prior knowledge 的连接前言也以大写字母开头
+	return p[0] >= 'A' && p[0] <= 'Z' && !bytes.HasPrefix(p, []byte("PRI * HTTP/2"))
+}
+
+// header 记录一个完整的头部，与 curl 一致，收到的头部每行单独记录
+func (s *traceStream) header(head []byte) {
+	if !s.incoming {
+		s.conn.tracer.dump(s.conn.id, "=> Send header", head)
+		return
+	}
+	for len(head) > 0 {
+		i := bytes.IndexByte(head, '\n') + 1
+		s.conn.tracer.dump(s.conn.id, "<= Recv header", head[:i])
+		head = head[i:]
+	}
+}
+
+// data 记录正文数据
+func (s *traceStream) data(p []byte) {
+	if len(p) == 0 {
+		return
+	}
+	if s.incoming {
+		s.conn.tracer.dump(s.conn.id, "<= Recv data", p)
+	} else {
+		s.conn.tracer.dump(s.conn.id, "=> Send data", p)
+	}
+}
+
+// next 根据头部判断之后的正文如何结束
+func (s *traceStream) next(head []byte) int {
+	reader := bufio.NewReader(bytes.NewReader(head))
+	if !s.incoming {
+		req, err := http.ReadRequest(reader)
+		if err != nil {
+			return streamRaw
+		}
+		s.conn.pushMethod(req.Method)
+		return s.bodyState(req.TransferEncoding, req.ContentLength, false)
+	}
+
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		return streamRaw
+	}
+	switch {
+	case resp.StatusCode == http.StatusSwitchingProtocols:
+		return streamRaw
+	case resp.StatusCode < 200:
+		// 1xx 之后还有最终响应
+		return streamHeader
+	}
+	method := s.conn.popMethod()
+	switch {
+	case method == http.MethodConnect && resp.StatusCode < 300:
+		// CONNECT 成功后是隧道数据
+		return streamRaw
+	case method == http.MethodHead, resp.StatusCode == http.StatusNoContent, resp.StatusCode == http.StatusNotModified:
+		return streamHeader
+	}
+	return s.bodyState(resp.TransferEncoding, resp.ContentLength, true)
+}
+
+// bodyState 返回正文对应的解析状态
+func (s *traceStream) bodyState(transferEncoding []string, contentLength int64, untilClose bool) int {
+	switch {
+	case len(transferEncoding) > 0 && transferEncoding[len(transferEncoding)-1] == "chunked":
+		return streamChunkSize
+	case contentLength > 0:
+		s.remaining = contentLength
+		return streamBody
+	case contentLength < 0 && untilClose:
+		// 没有长度的响应一直持续到连接关闭
+		return streamRaw
+	}
+	return streamHeader
+}
+
+// chunkLine 处理分块编码中的一行
+func (s *traceStream) chunkLine(line string) {
+	if s.state == streamChunkTrailer {
+		if line == "" {
+			s.state = streamHeader
+		}
+		return
+	}
+	if i := strings.IndexByte(line, ';'); i >= 0 {
+		line = line[:i]
+	}
+	var size int64
+	if _, err := fmt.Sscanf(line, "%x", &size); err != nil || size < 0 {
+		s.state = streamRaw
+		return
+	}
+	if size == 0 {
+		s.state = streamChunkTrailer
+		return
+	}
+	// 数据之后还有一个 CRLF
+	s.remaining = size + 2
+	s.state = streamChunkData
+}
+
+// configureTrace 在连接建立时接入 --trace 追踪
+// 数据需要在 TLS 解密之后记录，因此 TLS 握手由我们自己完成。
+// net/http 只有拿到 *tls.Conn 才会使用 HTTP/2：指定了 --http2 时 configureHTTPVersion 让 TLS 请求改由 x/net/http2 发送，
+// 自动选择时把 ALPN 限制为 http/1.1，避免协商出 h2 后仍然发送 HTTP/1.1 请求
+func (curl *CURL) configureTrace(ses *requests.Session) {
+	if !curl.Trace {
+		return
+	}
+	transport := sessionTransport(ses)
+	if transport == nil {
+		return
+	}
+
+	if curl.httpVersion() == HTTPVersionAuto {
+		disableHTTP2(transport)
+	}
+	dial := baseDialContext(transport)
+	transport.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if t := traceFromContext(ctx); t != nil {
+			return newTraceConn(t, conn), nil
+		}
+		return conn, nil
+	}
+	transport.DialTLSContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dialTLS(ctx, transport, dial, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		if t := traceFromContext(ctx); t != nil {
+			return newTraceConn(t, conn), nil
+		}
+		return conn, nil
+	}
+}
@@ -0,0 +1,182 @@
+package gcurl
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// runTrace 执行命令并返回 --trace 写到标准输出的内容
+func runTrace(t *testing.T, cmd string) string {
+	t.Helper()
+	curl, err := Parse(cmd)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var stdout bytes.Buffer
+	curl.Stdout = &stdout
+	if _, err := curl.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	return stdout.String()
+}
+
+func TestTraceDumpFormat(t *testing.T) {
+	data := []byte("GET / HTTP/1.1\r\nHost: a\r\n\r\n")
+
+	var hex bytes.Buffer
+	(&tracer{w: &hex}).dump(0, "=> Send header", data)
+	wantHex := "=> Send header, 27 bytes (0x1b)\n" +
+		"0000: 47 45 54 20 2f 20 48 54 54 50 2f 31 2e 31 0d 0a GET / HTTP/1.1..\n" +
+		"0010: 48 6f 73 74 3a 20 61 0d 0a 0d 0a                Host: a....\n"
+	if hex.String() != wantHex {
+		t.Errorf("hex dump =\n%s\nwant\n%s", hex.String(), wantHex)
+	}
+
+	var ascii bytes.Buffer
+	(&tracer{w: &ascii, ascii: true}).dump(0, "=> Send header", data)
+	wantASCII := "=> Send header, 27 bytes (0x1b)\n" +
+		"0000: GET / HTTP/1.1\n" +
+		"0010: Host: a\n" +
+		"0019: \n"
+	if ascii.String() != wantASCII {
+		t.Errorf("ascii dump =\n%s\nwant\n%s", ascii.String(), wantASCII)
+	}
+}
+
+func TestTraceExecution(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/chunked" {
+			w.Write([]byte("first"))
+			w.(http.Flusher).Flush()
+			w.Write([]byte("second"))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	t.Run("ASCII to file", func(t *testing.T) {
+		file := filepath.Join(t.TempDir(), "trace.txt")
+		runTrace(t, `curl --trace-ascii `+file+` -d hello `+srv.URL+`/path`)
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		assertInOrder(t, string(data), []string{
+			"== Info: Connected to 127.0.0.1 (127.0.0.1) port ",
+			"=> Send header, ",
+			"0000: POST /path HTTP/1.1\n",
+			"=> Send data, 5 bytes (0x5)\n0000: hello\n",
+			"<= Recv header, 17 bytes (0x11)\n0000: HTTP/1.1 200 OK\n",
+			"<= Recv header, 2 bytes (0x2)\n0000: \n",
+			"<= Recv data, 2 bytes (0x2)\n0000: ok\n",
+		})
+	})
+
+	t.Run("Hex to stdout", func(t *testing.T) {
+		out := runTrace(t, `curl --trace - `+srv.URL+`/`)
+		assertInOrder(t, out, []string{
+			"=> Send header, ",
+			"0000: 47 45 54 20 2f 20 48 54 54 50 2f 31 2e 31 0d 0a GET / HTTP/1.1..\n",
+			"<= Recv data, 2 bytes (0x2)\n0000: 6f 6b ",
+		})
+	})
+
+	t.Run("Chunked response", func(t *testing.T) {
+		out := runTrace(t, `curl --trace-ascii - `+srv.URL+`/chunked`)
+		assertInOrder(t, out, []string{
+			"<= Recv header, 28 bytes (0x1c)\n0000: Transfer-Encoding: chunked\n",
+			"<= Recv data, ",
+			"first",
+			"second",
+		})
+		if strings.Contains(out[strings.Index(out, "first"):], "Recv header") {
+			t.Errorf("chunked body recorded as header:\n%s", out)
+		}
+	})
+
+	t.Run("Time and IDs", func(t *testing.T) {
+		out := runTrace(t, `curl --trace-ascii - --trace-time --trace-ids `+srv.URL+`/`)
+		pattern := regexp.MustCompile(`(?m)^\d{2}:\d{2}:\d{2}\.\d{6} \[0-0\] => Send header, `)
+		if !pattern.MatchString(out) {
+			t.Errorf("missing timestamp and ids:\n%s", out)
+		}
+	})
+}
+
+func TestTraceTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("secret"))
+	}))
+	srv.EnableHTTP2 = true
+	srv.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	// 记录的是解密后的 HTTP/1.1 数据
+	out := runTrace(t, `curl -k --trace-ascii - `+srv.URL+`/secure`)
+	assertInOrder(t, out, []string{
+		"== Info: TLS handshake started\n",
+		"== Info: ALPN: server accepted http/1.1\n",
+		"0000: GET /secure HTTP/1.1\n",
+		"0000: HTTP/1.1 200 OK\n",
+		"0000: secret\n",
+	})
+
+	// --http2 仍然使用 h2，解密后的帧记录为 data
+	out = runTrace(t, `curl -k --http2 --trace-ascii - `+srv.URL+`/secure`)
+	assertInOrder(t, out, []string{
+		"== Info: ALPN: server accepted h2\n",
+		"=> Send data, ",
+		"0000: PRI * HTTP/2.0\n",
+		"<= Recv data, ",
+	})
+	if !strings.Contains(out, "secret") {
+		t.Errorf("response body missing from trace:\n%s", out)
+	}
+}
+
+func TestTraceProxyConnect(t *testing.T) {
+	_, securePort := proxyOrigins(t)
+	proxy := newHTTPProxyStandIn(t)
+
+	// CONNECT 请求和响应与隧道中解密后的数据记录在同一个连接编号下
+	out := runTrace(t, `curl -k --trace-ascii - --trace-ids -x `+proxy.URL+` https://origin.test:`+securePort+`/`)
+	assertInOrder(t, out, []string{
+		"[0-0] => Send header, ",
+		"0000: CONNECT origin.test:" + securePort + " HTTP/1.1\n",
+		"[0-0] <= Recv header, ",
+		"0000: HTTP/1.1 200 ",
+		"[0-0] => Send header, ",
+		"0000: GET / HTTP/1.1\n",
+		"0000: origin origin.test:" + securePort + "\n",
+	})
+	if strings.Contains(out, "[0-1]") {
+		t.Errorf("tunnel traced as a separate connection:\n%s", out)
+	}
+}
+
+func TestTraceOptions(t *testing.T) {
+	curl, err := Parse(`curl --trace-ascii out.txt --trace-time https://example.com`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if !curl.Trace || !curl.TraceASCII || curl.TraceFile != "out.txt" || !curl.TraceTime {
+		t.Errorf("unexpected trace options: trace=%v ascii=%v file=%q time=%v", curl.Trace, curl.TraceASCII, curl.TraceFile, curl.TraceTime)
+	}
+
+	curl, err = Parse(`curl --trace-ascii a.txt --trace b.txt https://example.com`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if curl.TraceASCII || curl.TraceFile != "b.txt" {
+		t.Errorf("last --trace should win: ascii=%v file=%q", curl.TraceASCII, curl.TraceFile)
+	}
+}
@@ -0,0 +1,111 @@
+package gcurl
+
+import (
+	"errors"
+	"net/http"
+	"reflect"
+	"time"
+	"unsafe"
+
+	"github.com/474420502/requests"
+)
+
+// errSessionInternals 表示无法访问 requests.Session 内部的 Transport 或 Client
+var errSessionInternals = errors.New("cannot configure the requests session: its internal transport or client is not accessible")
+
+// sessionTransport 返回 requests.Session 内部使用的 *http.Transport
+// requests 库没有暴露 Transport, 而拨号、TLS 等配置都需要直接操作它,
+// 因此这里通过反射读取未导出的 transport 字段；字段不存在或类型不符时返回 nil
+func sessionTransport(ses *requests.Session) *http.Transport {
+	field := reflect.ValueOf(ses).Elem().FieldByName("transport")
+	if !field.IsValid() || field.Type() != reflect.TypeOf((*http.Transport)(nil)) || field.IsNil() {
+		return nil
+	}
+	return (*http.Transport)(unsafe.Pointer(field.Pointer()))
+}
+
+// configureTransport 将 CURL 的网络选项应用到 Session 的 Transport 上
+func (curl *CURL) configureTransport(ses *requests.Session) {
+	// requests 默认每个请求使用新连接；与 curl 一致，默认复用连接，只有 --no-keepalive 关闭
+	ses.Config().SetKeepAlives(curl.KeepAlive)
+
+	transport := sessionTransport(ses)
+	if transport == nil {
+		return
+	}
+	transport.DialContext = curl.newDialer().DialContext
+	// 自定义 DialContext 后 net/http 不再自动启用 HTTP/2，与 curl 一致，HTTPS 默认通过 ALPN 协商 h2
+	transport.ForceAttemptHTTP2 = true
+}
+
+// unixSocketAddr 返回 --unix-socket 或 --abstract-unix-socket 对应的地址
+// Linux 抽象命名空间的套接字在 Go 中以 "@" 开头表示
+func (curl *CURL) unixSocketAddr() string {
+	if curl.UnixSocket == "" {
+		return ""
+	}
+	if curl.AbstractUnixSocket {
+		return "@" + curl.UnixSocket
+	}
+	return curl.UnixSocket
+}
+
+// keepAliveInterval 返回 net.Dialer.KeepAlive 的值，负数表示关闭TCP保活探测
+func (curl *CURL) keepAliveInterval() time.Duration {
+	if !curl.TCPKeepAlive {
+		return -1
+	}
+	return curl.KeepAliveTime
+}
+
+// configureTimeouts 设置各阶段的超时
+// 总超时（--max-time）由 http.Client 处理，连接和DNS超时由拨号器处理
+func (curl *CURL) configureTimeouts(ses *requests.Session) {
+	transport := sessionTransport(ses)
+	if transport == nil {
+		return
+	}
+
+	switch {
+	case curl.TLSHandshakeTimeout > 0:
+		transport.TLSHandshakeTimeout = curl.TLSHandshakeTimeout
+	case curl.ConnectTimeout > 0:
+		// 与 curl 一致，TLS 握手属于连接阶段
+		transport.TLSHandshakeTimeout = curl.ConnectTimeout
+	}
+}
+
+// sessionClient 返回 requests.Session 内部使用的 *http.Client
+// 需要在 Transport 外层包装认证等 RoundTripper 时使用
+func sessionClient(ses *requests.Session) *http.Client {
+	field := reflect.ValueOf(ses).Elem().FieldByName("client")
+	if !field.IsValid() || field.Type() != reflect.TypeOf((*http.Client)(nil)) || field.IsNil() {
+		return nil
+	}
+	return (*http.Client)(unsafe.Pointer(field.Pointer()))
+}
+
+// errorTransport 在执行请求时返回创建 Session 阶段产生的配置错误
+type errorTransport struct {
+	err error
+}
+
+// RoundTrip 实现 http.RoundTripper
+func (t errorTransport) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, t.err
+}
+
+// errorMiddleware 在无法替换 Transport 时，通过中间件让请求返回配置错误
+type errorMiddleware struct {
+	err error
+}
+
+// BeforeRequest 实现 requests.Middleware
+func (m errorMiddleware) BeforeRequest(*http.Request) error {
+	return m.err
+}
+
+// AfterResponse 实现 requests.Middleware
+func (m errorMiddleware) AfterResponse(*http.Response) error {
+	return nil
+}
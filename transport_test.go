@@ -0,0 +1,34 @@
+package gcurl
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/474420502/requests"
+)
+
+// TestSessionInternals 在 requests 的内部结构变化时失败，否则各项 configure 步骤都会静默失效
+func TestSessionInternals(t *testing.T) {
+	ses := requests.NewSession()
+	if sessionTransport(ses) == nil {
+		t.Error("sessionTransport cannot access the session's *http.Transport")
+	}
+	if sessionClient(ses) == nil {
+		t.Error("sessionClient cannot access the session's *http.Client")
+	}
+}
+
+func TestErrorMiddleware(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not be sent")
+	}))
+	defer srv.Close()
+
+	ses := requests.NewSession()
+	ses.AddMiddleware(errorMiddleware{err: errSessionInternals})
+	if _, err := ses.Get(srv.URL).Execute(); !errors.Is(err, errSessionInternals) {
+		t.Errorf("expected %v, got %v", errSessionInternals, err)
+	}
+}
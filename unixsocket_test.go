@@ -0,0 +1,95 @@
+package gcurl
+
+import (
+	"bytes"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// serveUnix 在 Unix 套接字上启动返回 Host 头的 HTTP 服务器
+func serveUnix(t *testing.T, addr string) {
+	t.Helper()
+	listener, err := net.Listen("unix", addr)
+	if err != nil {
+		t.Fatalf("listen on %s: %v", addr, err)
+	}
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host + " " + r.URL.Path))
+	})}
+	go srv.Serve(listener)
+	t.Cleanup(func() { srv.Close() })
+}
+
+func TestUnixSocket(t *testing.T) {
+	// Unix 套接字路径长度有限制，不使用较长的 t.TempDir()
+	dir, err := os.MkdirTemp("", "gcurl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "api.sock")
+	serveUnix(t, socket)
+
+	curl, err := Parse(`curl -v --unix-socket ` + socket + ` http://localhost/v1.43/containers/json`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var stderr bytes.Buffer
+	curl.Stderr = &stderr
+	resp, err := curl.Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := resp.ContentString(); got != "localhost /v1.43/containers/json" {
+		t.Errorf("response = %q", got)
+	}
+	assertInOrder(t, stderr.String(), []string{
+		"* Connected to localhost (" + socket + ") port 80\n",
+		"> Host: localhost\n",
+	})
+}
+
+func TestAbstractUnixSocket(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("abstract unix sockets are Linux only")
+	}
+	name := "gcurl-test-" + filepath.Base(t.TempDir())
+	serveUnix(t, "@"+name)
+
+	curl, err := Parse(`curl --abstract-unix-socket ` + name + ` http://sidecar.local:8080/health`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	resp, err := curl.Execute()
+	if err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	if got := resp.ContentString(); got != "sidecar.local:8080 /health" {
+		t.Errorf("response = %q", got)
+	}
+}
+
+func TestUnixSocketOptions(t *testing.T) {
+	curl, err := Parse(`curl --abstract-unix-socket a --unix-socket /tmp/b.sock http://localhost/`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if curl.UnixSocket != "/tmp/b.sock" || curl.AbstractUnixSocket {
+		t.Errorf("last option should win: %q abstract=%v", curl.UnixSocket, curl.AbstractUnixSocket)
+	}
+	if got := curl.unixSocketAddr(); got != "/tmp/b.sock" {
+		t.Errorf("unixSocketAddr = %q", got)
+	}
+
+	curl, err = Parse(`curl --abstract-unix-socket name http://localhost/`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if got := curl.unixSocketAddr(); got != "@name" {
+		t.Errorf("unixSocketAddr = %q, want @name", got)
+	}
+}
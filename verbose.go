@@ -0,0 +1,314 @@
+package gcurl
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"net/textproto"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// verboseContextKey 是请求 context 中 verboseLogger 的键，重定向时 checkRedirect 通过它输出中间响应
+type verboseContextKey struct{}
+
+// verboseLogger 在执行过程中按照 curl -v 的格式输出真实发生的事件
+//   - "* " 连接、DNS、TLS 等信息
+//   - "> " 实际发送的请求行和请求头
+//   - "< " 收到的状态行和响应头
+type verboseLogger struct {
+	mu      sync.Mutex
+	w       io.Writer
+	curl    *CURL
+	req     *http.Request // 当前正在发送的请求
+	host    string        // GetConn 得到的 host:port
+	proto   string        // 当前连接使用的协议
+	headers []string      // 已经写出的请求头
+	info    func(string)  // 不为 nil 时 "* " 信息行同时交给它，用于 --trace
+}
+
+// newVerboseLogger 创建输出到 w 的 verboseLogger
+func newVerboseLogger(curl *CURL, w io.Writer) *verboseLogger {
+	return &verboseLogger{w: w, curl: curl}
+}
+
+// withContext 在 ctx 上挂载 httptrace 和 logger 本身
+func (v *verboseLogger) withContext(ctx context.Context) context.Context {
+	ctx = context.WithValue(ctx, verboseContextKey{}, v)
+	return httptrace.WithClientTrace(ctx, v.clientTrace())
+}
+
+// verboseFromContext 返回 ctx 中的 verboseLogger，没有时返回 nil
+func verboseFromContext(ctx context.Context) *verboseLogger {
+	v, _ := ctx.Value(verboseContextKey{}).(*verboseLogger)
+	return v
+}
+
+// logf 以指定前缀输出一行
+func (v *verboseLogger) logf(prefix, format string, args ...interface{}) {
+	if prefix == "*" && v.info != nil {
+		v.info(fmt.Sprintf(format, args...))
+	}
+	fmt.Fprintf(v.w, v.timestamp()+prefix+" "+format+"\n", args...)
+}
+
+// infof 在 httptrace 事件之外输出一行 "* " 信息，例如代理握手的过程
+func (v *verboseLogger) infof(format string, args ...interface{}) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.logf("*", format, args...)
+}
+
+// timestamp 在指定了 --trace-time 时返回 curl 格式的时间戳前缀
+func (v *verboseLogger) timestamp() string {
+	if !v.curl.TraceTime {
+		return ""
+	}
+	now := time.Now()
+	return fmt.Sprintf("%s.%06d ", now.Format("15:04:05"), now.Nanosecond()/1000)
+}
+
+// clientTrace 返回输出各个阶段事件的 httptrace.ClientTrace
+func (v *verboseLogger) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		GetConn: func(hostPort string) {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			v.host = hostPort
+			v.headers = nil
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			v.proto = v.connProto(info.Conn)
+			if info.Reused {
+				v.logf("*", "Re-using existing connection with host %s", hostOnly(v.host))
+			}
+		},
+		DNSStart: func(info httptrace.DNSStartInfo) {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			v.logf("*", "Resolving %s...", info.Host)
+		},
+		DNSDone: func(info httptrace.DNSDoneInfo) {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			if info.Err != nil {
+				v.logf("*", "Could not resolve host: %s", info.Err)
+				return
+			}
+			v.logf("*", "Host %s was resolved.", v.host)
+			var v4, v6 []string
+			for _, addr := range info.Addrs {
+				if addr.IP.To4() != nil {
+					v4 = append(v4, addr.IP.String())
+				} else {
+					v6 = append(v6, addr.IP.String())
+				}
+			}
+			v.logf("*", "IPv6: %s", joinOrNone(v6))
+			v.logf("*", "IPv4: %s", joinOrNone(v4))
+		},
+		ConnectStart: func(network, addr string) {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			v.logf("*", "  Trying %s...", addr)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			ip, port, _ := net.SplitHostPort(addr)
+			if network == "unix" {
+				// 与 curl 一致，Unix 套接字显示路径和 URL 中的端口
+				ip, port = addr, portOnly(v.host)
+			}
+			if err != nil {
+				v.logf("*", "connect to %s port %s failed: %v", ip, port, err)
+				return
+			}
+			v.logf("*", "Connected to %s (%s) port %s", hostOnly(v.host), ip, port)
+		},
+		TLSHandshakeStart: func() {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			v.logf("*", "TLS handshake started")
+		},
+		TLSHandshakeDone: func(state tls.ConnectionState, err error) {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			if err != nil {
+				v.logf("*", "TLS handshake failed: %v", err)
+				return
+			}
+			v.logTLS(state)
+		},
+		WroteHeaderField: func(key string, value []string) {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			switch {
+			case key == ":authority":
+				key = "Host"
+			case strings.HasPrefix(key, ":"):
+				// HTTP/2 的其他伪头部已经体现在请求行中
+				return
+			}
+			for _, val := range value {
+				v.headers = append(v.headers, textproto.CanonicalMIMEHeaderKey(key)+": "+val)
+			}
+		},
+		WroteHeaders: func() {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			v.logRequest()
+		},
+		Got100Continue: func() {
+			v.mu.Lock()
+			defer v.mu.Unlock()
+			v.logf("<", "HTTP/1.1 100 Continue")
+		},
+	}
+}
+
+// connProto 根据连接和 --http* 选项判断请求行中的协议
+func (v *verboseLogger) connProto(conn net.Conn) string {
+	if tlsConn, ok := conn.(*tls.Conn); ok && tlsConn.ConnectionState().NegotiatedProtocol == "h2" {
+		return "HTTP/2"
+	}
+	switch v.curl.HTTPVersion {
+	case HTTPVersion2PriorKnowledge:
+		return "HTTP/2"
+	case HTTPVersion10:
+		return "HTTP/1.0"
+	}
+	return "HTTP/1.1"
+}
+
+// logTLS 输出 TLS 版本、加密套件、ALPN 和服务器证书信息
+func (v *verboseLogger) logTLS(state tls.ConnectionState) {
+	v.logf("*", "SSL connection using %s / %s", tlsVersionName(state.Version), tls.CipherSuiteName(state.CipherSuite))
+	if state.NegotiatedProtocol != "" {
+		v.logf("*", "ALPN: server accepted %s", state.NegotiatedProtocol)
+	} else {
+		v.logf("*", "ALPN: server did not agree on a protocol. Uses default.")
+	}
+	if len(state.PeerCertificates) == 0 {
+		return
+	}
+	cert := state.PeerCertificates[0]
+	v.logf("*", "Server certificate:")
+	v.logf("*", " subject: %s", cert.Subject)
+	v.logf("*", " start date: %s", cert.NotBefore.UTC().Format("Jan _2 15:04:05 2006 GMT"))
+	v.logf("*", " expire date: %s", cert.NotAfter.UTC().Format("Jan _2 15:04:05 2006 GMT"))
+	if len(cert.DNSNames) > 0 {
+		v.logf("*", " subjectAltName: %s", strings.Join(cert.DNSNames, ", "))
+	}
+	v.logf("*", " issuer: %s", cert.Issuer)
+	if v.curl.Insecure {
+		v.logf("*", " SSL certificate verify result: skipped (--insecure)")
+	} else {
+		v.logf("*", " SSL certificate verify ok.")
+	}
+}
+
+// logRequest 输出请求行和实际写出的请求头
+func (v *verboseLogger) logRequest() {
+	method, target := "GET", "/"
+	if v.req != nil {
+		method = v.req.Method
+		target = v.req.URL.RequestURI()
+	}
+	v.logf(">", "%s %s %s", method, target, v.proto)
+	for _, line := range v.headers {
+		v.logf(">", "%s", line)
+	}
+	fmt.Fprintln(v.w, v.timestamp()+">")
+	v.headers = nil
+}
+
+// logResponse 输出状态行和响应头
+func (v *verboseLogger) logResponse(resp *http.Response) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.logf("<", "%s", strings.TrimRight(statusLine(resp), " "))
+	keys := make([]string, 0, len(resp.Header))
+	for key := range resp.Header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		for _, value := range resp.Header[key] {
+			v.logf("<", "%s: %s", key, value)
+		}
+	}
+	fmt.Fprintln(v.w, v.timestamp()+"<")
+}
+
+// redirect 在跟随重定向前输出中间响应，并记录下一个请求
+func (v *verboseLogger) redirect(req *http.Request) {
+	if req.Response != nil {
+		v.logResponse(req.Response)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.logf("*", "Issue another request to this URL: '%s'", req.URL)
+	v.req = req
+}
+
+// BeforeRequest 实现 requests.Middleware，记录第一个请求
+func (v *verboseLogger) BeforeRequest(req *http.Request) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.req = req
+	return nil
+}
+
+// AfterResponse 实现 requests.Middleware，输出最终响应
+func (v *verboseLogger) AfterResponse(resp *http.Response) error {
+	v.logResponse(resp)
+	return nil
+}
+
+// tlsVersionName 返回 curl 风格的 TLS 版本名称
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionTLS10:
+		return "TLSv1.0"
+	case tls.VersionTLS11:
+		return "TLSv1.1"
+	case tls.VersionTLS12:
+		return "TLSv1.2"
+	case tls.VersionTLS13:
+		return "TLSv1.3"
+	}
+	return fmt.Sprintf("TLS 0x%04x", version)
+}
+
+// hostOnly 去掉 host:port 中的端口
+func hostOnly(hostPort string) string {
+	if host, _, err := net.SplitHostPort(hostPort); err == nil {
+		return host
+	}
+	return hostPort
+}
+
+// portOnly 返回 host:port 中的端口
+func portOnly(hostPort string) string {
+	if _, port, err := net.SplitHostPort(hostPort); err == nil {
+		return port
+	}
+	return ""
+}
+
+// joinOrNone 以逗号连接地址，没有地址时与 curl 一致输出 (none)
+func joinOrNone(addrs []string) string {
+	if len(addrs) == 0 {
+		return "(none)"
+	}
+	return strings.Join(addrs, ", ")
+}
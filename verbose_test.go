@@ -0,0 +1,131 @@
+package gcurl
+
+import (
+	"bytes"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// runVerbose 执行命令并返回 -v 写到标准错误的内容
+func runVerbose(t *testing.T, cmd string) string {
+	t.Helper()
+	curl, err := Parse(cmd)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var stderr bytes.Buffer
+	curl.Stderr = &stderr
+	if _, err := curl.Execute(); err != nil {
+		t.Fatalf("Execute failed: %v", err)
+	}
+	return stderr.String()
+}
+
+// assertInOrder 确认 want 中的每一行按顺序出现在 output 中
+func assertInOrder(t *testing.T, output string, want []string) {
+	t.Helper()
+	rest := output
+	for _, line := range want {
+		idx := strings.Index(rest, line)
+		if idx < 0 {
+			t.Errorf("missing %q (in order) in verbose output:\n%s", line, output)
+			return
+		}
+		rest = rest[idx+len(line):]
+	}
+}
+
+func TestVerboseExecution(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/redirect" {
+			http.Redirect(w, r, "/final?x=1", http.StatusFound)
+			return
+		}
+		w.Header().Set("X-Test", "value")
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+	hostPort := strings.TrimPrefix(srv.URL, "http://")
+	port := hostPort[strings.LastIndex(hostPort, ":")+1:]
+
+	t.Run("Request and response", func(t *testing.T) {
+		out := runVerbose(t, `curl -v -H "X-Custom: abc" `+srv.URL+`/path`)
+		assertInOrder(t, out, []string{
+			"*   Trying " + hostPort + "...\n",
+			"* Connected to 127.0.0.1 (127.0.0.1) port " + port + "\n",
+			"> GET /path HTTP/1.1\n",
+			"> Host: " + hostPort + "\n",
+			"> X-Custom: abc\n",
+			">\n",
+			"< HTTP/1.1 200 OK\n",
+			"< X-Test: value\n",
+			"<\n",
+		})
+	})
+
+	t.Run("Redirect hops", func(t *testing.T) {
+		out := runVerbose(t, `curl -v -L `+srv.URL+`/redirect`)
+		assertInOrder(t, out, []string{
+			"> GET /redirect HTTP/1.1\n",
+			"< HTTP/1.1 302 Found\n",
+			"< Location: /final?x=1\n",
+			"* Issue another request to this URL: '" + srv.URL + "/final?x=1'\n",
+			"> GET /final?x=1 HTTP/1.1\n",
+			"< HTTP/1.1 200 OK\n",
+		})
+	})
+
+	t.Run("DNS resolution", func(t *testing.T) {
+		out := runVerbose(t, `curl -v http://localhost:`+port+`/`)
+		assertInOrder(t, out, []string{
+			"* Host localhost:" + port + " was resolved.\n",
+			"* IPv4: 127.0.0.1",
+			"> GET / HTTP/1.1\n",
+		})
+	})
+
+	t.Run("Silent without -v", func(t *testing.T) {
+		if out := runVerbose(t, `curl `+srv.URL); out != "" {
+			t.Errorf("unexpected output without -v: %q", out)
+		}
+	})
+}
+
+func TestVerboseTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	srv.EnableHTTP2 = true
+	srv.TLS = &tls.Config{NextProtos: []string{"h2", "http/1.1"}}
+	srv.StartTLS()
+	defer srv.Close()
+
+	out := runVerbose(t, `curl -v -k --http2 `+srv.URL+`/secure`)
+	assertInOrder(t, out, []string{
+		"* TLS handshake started\n",
+		"* SSL connection using TLSv1.3 / TLS_",
+		"* ALPN: server accepted h2\n",
+		"* Server certificate:\n",
+		"*  subject: O=Acme Co\n",
+		"*  issuer: O=Acme Co\n",
+		"> GET /secure HTTP/2\n",
+		"< HTTP/2 200\n",
+	})
+}
+
+func TestVerboseInfoIsPreview(t *testing.T) {
+	curl, err := Parse(`curl --http1.0 https://example.com/a`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	info := curl.VerboseInfo()
+	if strings.Contains(info, "Connected to") {
+		t.Errorf("preview should not claim a connection:\n%s", info)
+	}
+	if !strings.Contains(info, "> GET /a HTTP/1.0") {
+		t.Errorf("preview should use the selected HTTP version:\n%s", info)
+	}
+}
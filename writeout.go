@@ -0,0 +1,350 @@
+package gcurl
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptrace"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/474420502/requests"
+)
+
+// transferStats 记录一次传输的时间点和数据量，用于 --write-out
+// 重定向时各个时间点记录的是最后一跳的值，与起点 start 相减得到 curl 的 time_* 变量
+type transferStats struct {
+	mu          sync.Mutex
+	start       time.Time
+	dnsDone     time.Time
+	connectDone time.Time
+	tlsDone     time.Time
+	firstByte   time.Time
+	end         time.Time
+	remoteAddr  string
+
+	sizeDownload int64
+	sizeUpload   int64
+}
+
+// newTransferStats 创建从当前时间开始计时的 transferStats
+func newTransferStats() *transferStats {
+	return &transferStats{start: time.Now()}
+}
+
+// finish 记录传输结束的时间
+func (s *transferStats) finish() {
+	s.mu.Lock()
+	s.end = time.Now()
+	s.mu.Unlock()
+}
+
+// mark 在持有锁的情况下记录时间点
+func (s *transferStats) mark(t *time.Time) {
+	s.mu.Lock()
+	*t = time.Now()
+	s.mu.Unlock()
+}
+
+// clientTrace 返回记录各个阶段时间点的 httptrace.ClientTrace
+func (s *transferStats) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSDone: func(httptrace.DNSDoneInfo) { s.mark(&s.dnsDone) },
+		ConnectDone: func(network, addr string, err error) {
+			if err == nil {
+				s.mark(&s.connectDone)
+			}
+		},
+		TLSHandshakeDone: func(_ tls.ConnectionState, err error) {
+			if err == nil {
+				s.mark(&s.tlsDone)
+			}
+		},
+		GotConn: func(info httptrace.GotConnInfo) {
+			s.mu.Lock()
+			s.remoteAddr = info.Conn.RemoteAddr().String()
+			s.mu.Unlock()
+		},
+		GotFirstResponseByte: func() { s.mark(&s.firstByte) },
+	}
+}
+
+// since 返回时间点相对于开始的秒数，未发生的阶段为 0
+func (s *transferStats) since(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Sub(s.start).Seconds()
+}
+
+// statsMiddleware 统计实际发送的请求体和收到的响应体字节数
+type statsMiddleware struct {
+	stats *transferStats
+}
+
+// BeforeRequest 实现 requests.Middleware
+func (m *statsMiddleware) BeforeRequest(req *http.Request) error {
+	if req.Body != nil && req.Body != http.NoBody {
+		req.Body = &countingReader{reader: req.Body, count: &m.stats.sizeUpload}
+	}
+	return nil
+}
+
+// AfterResponse 实现 requests.Middleware
+func (m *statsMiddleware) AfterResponse(resp *http.Response) error {
+	resp.Body = &countingReader{reader: resp.Body, count: &m.stats.sizeDownload}
+	return nil
+}
+
+// countingReader 统计读取的字节数
+type countingReader struct {
+	reader io.ReadCloser
+	count  *int64
+}
+
+// Read 实现 io.Reader
+func (r *countingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	atomic.AddInt64(r.count, int64(n))
+	return n, err
+}
+
+// Close 实现 io.Closer
+func (r *countingReader) Close() error {
+	return r.reader.Close()
+}
+
+// exitCoder 由带有 curl 退出码的错误实现，例如 MaxFileSizeError
+type exitCoder interface {
+	ExitCode() int
+}
+
+// exitCode 返回与本次执行结果对应的 curl 退出码
+func (curl *CURL) exitCode(resp *requests.Response, err error) int {
+	if err == nil {
+		if curl.FailOnError && resp != nil && resp.GetStatusCode() >= 400 {
+			return 22
+		}
+		return 0
+	}
+
+	var coder exitCoder
+	var dnsErr *net.DNSError
+	var unknownAuthority x509.UnknownAuthorityError
+	var certInvalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	switch {
+	case errors.As(err, &coder):
+		return coder.ExitCode()
+	case isTimeoutError(err):
+		return 28
+	case errors.As(err, &dnsErr):
+		return 6
+	case errors.Is(err, syscall.ECONNREFUSED):
+		return 7
+	case errors.As(err, &unknownAuthority), errors.As(err, &certInvalid), errors.As(err, &hostname):
+		return 60
+	}
+	// 其他错误归为接收数据失败
+	return 56
+}
+
+// errorMessage 返回与 exitCode 对应的错误信息
+func (curl *CURL) errorMessage(resp *requests.Response, err error) string {
+	if err != nil {
+		return err.Error()
+	}
+	if curl.FailOnError && resp != nil && resp.GetStatusCode() >= 400 {
+		return fmt.Sprintf("The requested URL returned error: %d", resp.GetStatusCode())
+	}
+	return ""
+}
+
+// writeOutVariables 返回 --write-out 支持的所有变量
+// 数值类型的变量保持数值，以便 %{json} 输出正确的 JSON 类型
+func (curl *CURL) writeOutVariables(resp *requests.Response, err error, stats *transferStats) map[string]interface{} {
+	if stats == nil {
+		stats = newTransferStats()
+		stats.finish()
+	}
+	stats.mu.Lock()
+	defer stats.mu.Unlock()
+
+	total := stats.since(stats.end)
+	sizeDownload := atomic.LoadInt64(&stats.sizeDownload)
+	vars := map[string]interface{}{
+		"time_namelookup":    stats.since(stats.dnsDone),
+		"time_connect":       stats.since(stats.connectDone),
+		"time_appconnect":    stats.since(stats.tlsDone),
+		"time_starttransfer": stats.since(stats.firstByte),
+		"time_total":         total,
+		"size_download":      sizeDownload,
+		"size_upload":        atomic.LoadInt64(&stats.sizeUpload),
+		"speed_download":     int64(0),
+		"http_code":          0,
+		"response_code":      0,
+		"num_redirects":      0,
+		"url_effective":      "",
+		"content_type":       "",
+		"http_version":       "0",
+		"remote_ip":          "",
+		"remote_port":        "",
+		"exitcode":           curl.exitCode(resp, err),
+		"errormsg":           curl.errorMessage(resp, err),
+	}
+	if total > 0 {
+		vars["speed_download"] = int64(float64(sizeDownload) / total)
+	}
+	if curl.ParsedURL != nil {
+		vars["url_effective"] = curl.ParsedURL.String()
+	}
+	if host, port, splitErr := net.SplitHostPort(stats.remoteAddr); splitErr == nil {
+		vars["remote_ip"] = host
+		vars["remote_port"] = port
+	}
+
+	if resp != nil && resp.GetResponse() != nil {
+		raw := resp.GetResponse()
+		vars["http_code"] = raw.StatusCode
+		vars["response_code"] = raw.StatusCode
+		vars["num_redirects"] = len(redirectChain(raw)) - 1
+		vars["content_type"] = raw.Header.Get("Content-Type")
+		if raw.Request != nil {
+			vars["url_effective"] = raw.Request.URL.String()
+		}
+		if raw.ProtoMajor == 2 {
+			vars["http_version"] = "2"
+		} else if raw.ProtoMajor > 0 {
+			vars["http_version"] = fmt.Sprintf("%d.%d", raw.ProtoMajor, raw.ProtoMinor)
+		}
+	}
+	return vars
+}
+
+// formatWriteOutValue 按照 curl 的格式输出变量值
+func formatWriteOutValue(name string, value interface{}) string {
+	switch v := value.(type) {
+	case float64:
+		return strconv.FormatFloat(v, 'f', 6, 64)
+	case int:
+		if name == "http_code" || name == "response_code" {
+			return fmt.Sprintf("%03d", v)
+		}
+		return strconv.Itoa(v)
+	case int64:
+		return strconv.FormatInt(v, 10)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeOut 按照 -w 的格式输出最后一次传输的信息
+// 支持 %{变量}、%{json}、%header{名称}、%{stdout}/%{stderr} 切换输出目标，以及 \n \r \t 转义
+func (curl *CURL) writeOut(resp *requests.Response, err error, stats *transferStats) error {
+	if curl.writeOutStdin {
+		data, rerr := io.ReadAll(curl.stdin())
+		if rerr != nil {
+			return fmt.Errorf("failed to read write-out format from -: %w", rerr)
+		}
+		curl.WriteOutFormat = string(data)
+		curl.writeOutStdin = false
+	}
+
+	vars := curl.writeOutVariables(resp, err, stats)
+	var header http.Header
+	if resp != nil && resp.GetResponse() != nil {
+		header = resp.GetResponse().Header
+	}
+
+	out := curl.stdout()
+	var buf bytes.Buffer
+	flush := func() error {
+		if buf.Len() == 0 {
+			return nil
+		}
+		_, werr := out.Write(buf.Bytes())
+		buf.Reset()
+		return werr
+	}
+
+	format := curl.WriteOutFormat
+	for i := 0; i < len(format); i++ {
+		ch := format[i]
+		switch {
+		case ch == '\\' && i+1 < len(format):
+			switch format[i+1] {
+			case 'n':
+				buf.WriteByte('\n')
+			case 'r':
+				buf.WriteByte('\r')
+			case 't':
+				buf.WriteByte('\t')
+			case '\\':
+				buf.WriteByte('\\')
+			default:
+				buf.WriteByte(ch)
+				continue
+			}
+			i++
+		case ch == '%' && strings.HasPrefix(format[i:], "%%"):
+			buf.WriteByte('%')
+			i++
+		case ch == '%' && strings.HasPrefix(format[i:], "%{"):
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				buf.WriteString(format[i:])
+				i = len(format)
+				break
+			}
+			name := format[i+2 : i+end]
+			i += end
+			switch name {
+			case "stdout", "stderr":
+				if werr := flush(); werr != nil {
+					return werr
+				}
+				if name == "stdout" {
+					out = curl.stdout()
+				} else {
+					out = curl.stderr()
+				}
+			case "json":
+				data, jerr := json.Marshal(vars)
+				if jerr != nil {
+					return jerr
+				}
+				buf.Write(data)
+			default:
+				// 与 curl 一致，未知变量不输出任何内容
+				if value, ok := vars[name]; ok {
+					buf.WriteString(formatWriteOutValue(name, value))
+				}
+			}
+		case ch == '%' && strings.HasPrefix(format[i:], "%header{"):
+			end := strings.IndexByte(format[i:], '}')
+			if end < 0 {
+				buf.WriteString(format[i:])
+				i = len(format)
+				break
+			}
+			name := format[i+len("%header{") : i+end]
+			i += end
+			if header != nil {
+				buf.WriteString(strings.Join(header.Values(name), ", "))
+			}
+		default:
+			buf.WriteByte(ch)
+		}
+	}
+	return flush()
+}
@@ -0,0 +1,214 @@
+package gcurl
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// writeOutTestServer 在 /redirect 重定向到 /final，/missing 返回 404
+func writeOutTestServer(t *testing.T) *httptest.Server {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/redirect":
+			http.Redirect(w, r, "/final", http.StatusFound)
+		case "/missing":
+			http.NotFound(w, r)
+		default:
+			w.Header().Set("Content-Type", "application/json")
+			w.Header().Add("X-Multi", "a")
+			w.Header().Add("X-Multi", "b")
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"ok":true}`))
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+// runWriteOut 执行命令并返回 -w 写到标准输出和标准错误的内容
+func runWriteOut(t *testing.T, cmd string) (string, string) {
+	t.Helper()
+	curl, err := Parse(cmd)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	var stdout, stderr bytes.Buffer
+	curl.Stdout = &stdout
+	curl.Stderr = &stderr
+	curl.Execute()
+	return stdout.String(), stderr.String()
+}
+
+func TestWriteOutVariables(t *testing.T) {
+	srv := writeOutTestServer(t)
+	port := srv.URL[strings.LastIndex(srv.URL, ":")+1:]
+
+	tests := []struct {
+		name   string
+		format string
+		path   string
+		extra  string
+		want   string
+	}{
+		{"Status and type", `%{http_code} %{response_code} %{content_type}`, "/final", "", "201 201 application/json"},
+		{"Redirects", `%{num_redirects} %{url_effective}`, "/redirect", "-L", "1 " + srv.URL + "/final"},
+		{"Not following redirects", `%{http_code} %{num_redirects}`, "/redirect", "", "302 0"},
+		{"Sizes", `%{size_download} %{size_upload}`, "/final", "-d hello", "11 5"},
+		{"Connection", `%{remote_ip} %{remote_port} %{http_version}`, "/final", "", "127.0.0.1 " + port + " 1.1"},
+		{"Header", `%header{x-multi}|%header{missing}`, "/final", "", "a, b|"},
+		{"Escapes and percent", `a\nb\tc 100%%`, "/final", "", "a\nb\tc 100%"},
+		{"Unknown variable", `[%{nope}]`, "/final", "", "[]"},
+		{"Success exit code", `%{exitcode}[%{errormsg}]`, "/final", "", "0[]"},
+		{"Fail exit code", `%{exitcode} %{errormsg}`, "/missing", "-f", "22 The requested URL returned error: 404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out, _ := runWriteOut(t, `curl -s `+tt.extra+` -w '`+tt.format+`' `+srv.URL+tt.path)
+			if out != tt.want {
+				t.Errorf("write-out = %q, want %q", out, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteOutTimings(t *testing.T) {
+	srv := writeOutTestServer(t)
+
+	out, _ := runWriteOut(t, `curl -w '%{time_namelookup} %{time_connect} %{time_appconnect} %{time_starttransfer} %{time_total} %{speed_download}' `+srv.URL)
+	fields := strings.Fields(out)
+	if len(fields) != 6 {
+		t.Fatalf("unexpected output: %q", out)
+	}
+	var times []float64
+	for _, f := range fields[:5] {
+		v, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			t.Fatalf("invalid time %q: %v", f, err)
+		}
+		times = append(times, v)
+	}
+	if times[1] <= 0 || times[3] < times[1] || times[4] < times[3] {
+		t.Errorf("timings out of order: %v", times)
+	}
+	if times[2] != 0 {
+		t.Errorf("time_appconnect = %v, want 0 for plain HTTP", times[2])
+	}
+	if speed, err := strconv.Atoi(fields[5]); err != nil || speed <= 0 {
+		t.Errorf("speed_download = %q", fields[5])
+	}
+}
+
+func TestWriteOutJSON(t *testing.T) {
+	srv := writeOutTestServer(t)
+
+	out, _ := runWriteOut(t, `curl -w '%{json}' `+srv.URL)
+	var vars map[string]interface{}
+	if err := json.Unmarshal([]byte(out), &vars); err != nil {
+		t.Fatalf("invalid JSON %q: %v", out, err)
+	}
+	if vars["http_code"] != float64(201) {
+		t.Errorf("http_code = %v, want 201", vars["http_code"])
+	}
+	if vars["content_type"] != "application/json" {
+		t.Errorf("content_type = %v", vars["content_type"])
+	}
+	for _, key := range []string{"time_total", "size_download", "url_effective", "exitcode", "errormsg", "remote_ip"} {
+		if _, ok := vars[key]; !ok {
+			t.Errorf("missing %s in %%{json}", key)
+		}
+	}
+}
+
+func TestWriteOutStreams(t *testing.T) {
+	srv := writeOutTestServer(t)
+
+	out, errOut := runWriteOut(t, `curl -w 'one %{stderr}two %{http_code}%{stdout} three' `+srv.URL)
+	if out != "one  three" {
+		t.Errorf("stdout = %q", out)
+	}
+	if errOut != "two 201" {
+		t.Errorf("stderr = %q", errOut)
+	}
+}
+
+func TestWriteOutFromFile(t *testing.T) {
+	srv := writeOutTestServer(t)
+	formatFile := filepath.Join(t.TempDir(), "format.txt")
+	if err := os.WriteFile(formatFile, []byte("code=%{http_code}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	out, _ := runWriteOut(t, `curl -w @`+formatFile+` `+srv.URL)
+	if out != "code=201\n" {
+		t.Errorf("write-out = %q", out)
+	}
+
+	if _, err := Parse(`curl -w @/nonexistent/format.txt https://example.com`); err == nil {
+		t.Error("Expected error for missing format file")
+	}
+}
+
+func TestWriteOutFromStdin(t *testing.T) {
+	srv := writeOutTestServer(t)
+
+	// Parse 不能读取进程的标准输入
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("untouched")
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() {
+		os.Stdin = stdin
+		r.Close()
+	}()
+
+	curl, err := Parse(`curl -w @- ` + srv.URL)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if data, _ := io.ReadAll(r); string(data) != "untouched" {
+		t.Errorf("Parse read from os.Stdin, left %q", data)
+	}
+
+	var stdout bytes.Buffer
+	curl.Stdout = &stdout
+	curl.Stdin = strings.NewReader("code=%{http_code}\n")
+	curl.Execute()
+	// 格式只读取一次，再次执行时复用
+	curl.Execute()
+	if got := stdout.String(); got != "code=201\ncode=201\n" {
+		t.Errorf("write-out = %q", got)
+	}
+}
+
+func TestWriteOutExitCodes(t *testing.T) {
+	curl := New()
+	tests := []struct {
+		err  error
+		want int
+	}{
+		{nil, 0},
+		{&MaxFileSizeError{Limit: 1, Size: 2}, 63},
+		{fmt.Errorf("request execution failed: %w", &TooManyRedirectsError{Max: 3}), 47},
+		{errors.New("something else"), 56},
+	}
+	for _, tt := range tests {
+		if got := curl.exitCode(nil, tt.err); got != tt.want {
+			t.Errorf("exitCode(%v) = %d, want %d", tt.err, got, tt.want)
+		}
+	}
+}